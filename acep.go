@@ -0,0 +1,140 @@
+package epaper
+
+import (
+	"bytes"
+	"context"
+	"image/color"
+	"time"
+)
+
+// Commands of the controllers of the 7-color panels, besides the ones of the UC81xx family.
+const (
+	acepPowerOffSequence    byte = 0x03
+	acepBoosterSoftStart1   byte = 0x05
+	acepBoosterSoftStart2   byte = 0x08
+	acepImageProcess        byte = 0x13 // Not DTM2: these controllers have a single frame buffer
+	acepTemperatureBoundary byte = 0x84
+	acepActiveGate          byte = 0x86
+	acepCommandHeader       byte = 0xaa
+	acepCascadeSetting      byte = 0xe0
+	acepPowerSaving         byte = 0xe3
+	acepForceTemperature    byte = 0xe6
+)
+
+// acepPalette contains the inks of the 7-color panels, in the order of the values packed in the frames.
+var acepPalette = color.Palette{
+	color.RGBA{A: 0xff},                            // Black
+	color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, // White
+	color.RGBA{G: 0xff, A: 0xff},                   // Green
+	color.RGBA{B: 0xff, A: 0xff},                   // Blue
+	color.RGBA{R: 0xff, A: 0xff},                   // Red
+	color.RGBA{R: 0xff, G: 0xff, A: 0xff},          // Yellow
+	color.RGBA{R: 0xff, G: 0x80, A: 0xff},          // Orange
+}
+
+// acep drives the 7-color panels (Advanced Color ePaper), which take 4 bits per pixel. The controller is powered on
+// for each refresh only.
+type acep struct {
+	bus         Bus
+	model       Model
+	setup       []command // Sent after the reset
+	refreshData []byte    // Data of the DRF command
+}
+
+// newEpd5in65f creates the driver for the 7-color 5.65 inches panel.
+func newEpd5in65f(b Bus, m Model) Driver {
+	w, h := m.Width, m.Height
+	return &acep{
+		bus:   b,
+		model: m,
+		setup: []command{
+			{CmdPanelSetting, []byte{0xef, 0x08}},
+			{CmdPowerSetting, []byte{0x37, 0x00, 0x23, 0x23}},
+			{acepPowerOffSequence, []byte{0x00}},
+			{CmdBoosterSoftStart, []byte{0xc7, 0xc7, 0x1d}},
+			{CmdPllControl, []byte{0x3c}},
+			{CmdTemperatureCalibration, []byte{0x00}},
+			{CmdVcomDataIntervalSet, []byte{0x37}},
+			{CmdTconSetting, []byte{0x22}},
+			{CmdTconResolution, []byte{byte(w >> 8), byte(w), byte(h >> 8), byte(h)}},
+			{acepPowerSaving, []byte{0xaa}},
+		},
+	}
+}
+
+// newEpd7in3f creates the driver for the 7-color 7.3 inches panel.
+func newEpd7in3f(b Bus, m Model) Driver {
+	w, h := m.Width, m.Height
+	return &acep{
+		bus:   b,
+		model: m,
+		setup: []command{
+			{acepCommandHeader, []byte{0x49, 0x55, 0x20, 0x08, 0x09, 0x18}},
+			{CmdPowerSetting, []byte{0x3f, 0x00, 0x32, 0x2a, 0x0e, 0x2a}},
+			{CmdPanelSetting, []byte{0x5f, 0x69}},
+			{acepPowerOffSequence, []byte{0x00, 0x54, 0x00, 0x44}},
+			{acepBoosterSoftStart1, []byte{0x40, 0x1f, 0x1f, 0x2c}},
+			{CmdBoosterSoftStart, []byte{0x6f, 0x1f, 0x1f, 0x22}},
+			{acepBoosterSoftStart2, []byte{0x6f, 0x1f, 0x1f, 0x22}},
+			{acepImageProcess, []byte{0x00, 0x04}},
+			{CmdPllControl, []byte{0x3c}},
+			{CmdTemperatureCalibration, []byte{0x00}},
+			{CmdVcomDataIntervalSet, []byte{0x3f}},
+			{CmdTconSetting, []byte{0x02, 0x00}},
+			{CmdTconResolution, []byte{byte(w >> 8), byte(w), byte(h >> 8), byte(h)}},
+			{CmdVcmDcSetting, []byte{0x1e}},
+			{acepTemperatureBoundary, []byte{0x00}},
+			{acepActiveGate, []byte{0x00}},
+			{acepPowerSaving, []byte{0x2f}},
+			{acepCascadeSetting, []byte{0x00}},
+			{acepForceTemperature, []byte{0x00}},
+		},
+		refreshData: []byte{0x00},
+	}
+}
+
+func (d *acep) Capabilities() Capabilities {
+	return Capabilities{
+		Palette: acepPalette,
+		Format:  Format4bpp,
+	}
+}
+
+func (d *acep) Init(ctx context.Context) error {
+	if err := d.bus.Reset(); err != nil {
+		return err
+	}
+	if err := d.bus.WaitUntilIdle(ctx); err != nil {
+		return err
+	}
+	if err := sendSequence(d.bus, d.setup); err != nil {
+		return err
+	}
+	time.Sleep(100 * time.Millisecond)
+	return nil
+}
+
+func (d *acep) Clear(ctx context.Context) error {
+	// Each byte contains 2 white pixels.
+	return d.bus.Send(CmdDataStartTransimission1, bytes.Repeat([]byte{0x11}, Format4bpp.stride(d.model.Width)*d.model.Height))
+}
+
+func (d *acep) WriteFrame(ctx context.Context, frame []byte) error {
+	return d.bus.Send(CmdDataStartTransimission1, frame)
+}
+
+func (d *acep) Refresh(ctx context.Context) error {
+	for _, c := range []command{{CmdPowerOn, nil}, {CmdDisplayRefresh, d.refreshData}, {CmdPowerOff, d.refreshData}} {
+		if err := d.bus.Send(c.cmd, c.data); err != nil {
+			return err
+		}
+		if err := d.bus.WaitUntilIdle(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *acep) Sleep(ctx context.Context) error {
+	return d.bus.Send(CMdDeepSleep, []byte{0xA5})
+}
@@ -0,0 +1,83 @@
+package epaper_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/mcules/go-epaper-lib"
+	"periph.io/x/periph/conn/gpio"
+)
+
+func TestSevenColorPanels(t *testing.T) {
+	inks := []color.Color{
+		color.Black,
+		color.White,
+		color.RGBA{G: 0xff, A: 0xff},
+		color.RGBA{B: 0xff, A: 0xff},
+		color.RGBA{R: 0xff, A: 0xff},
+		color.RGBA{R: 0xff, G: 0xff, A: 0xff},
+		color.RGBA{R: 0xff, G: 0x80, A: 0xff},
+	}
+
+	for _, model := range []epaper.Model{epaper.Model5in65f, epaper.Model7in3f} {
+		e, _ := newDriverTestEPaper(t, model, gpio.High)
+		if err := e.ClearScreen(); err != nil {
+			t.Fatal(err)
+		}
+		em := e.Emulator()
+		screen := image.Rect(0, 0, model.Width, model.Height)
+		if n := countColor(em.Image(), screen, color.White); n != model.Width*model.Height {
+			t.Fatalf("%dx%d: expected a white screen after clearing it, found %d white pixels", model.Width, model.Height, n)
+		}
+
+		// A stripe of each ink, with slightly different colors.
+		for i, ink := range inks {
+			r, g, b, _ := ink.RGBA()
+			shade := color.RGBA{R: uint8(r>>8) &^ 0x0f, G: uint8(g>>8) &^ 0x0f, B: uint8(b>>8) | 0x0f, A: 0xff}
+			if b != 0 {
+				shade.B = 0xf0
+			}
+			draw.Draw(e.Display, image.Rect(i*10, 0, i*10+10, 20), image.NewUniform(shade), image.Point{}, draw.Src)
+		}
+		if err := e.PrintDisplay(); err != nil {
+			t.Fatal(err)
+		}
+
+		img := em.Image()
+		for i, ink := range inks {
+			if n := countColor(img, image.Rect(i*10, 0, i*10+10, 20), ink); n != 200 {
+				t.Fatalf("%dx%d: expected stripe %d to be printed with ink %v, found %d pixels", model.Width, model.Height, i, ink, n)
+			}
+		}
+	}
+}
+
+func TestDither(t *testing.T) {
+	e, _ := newDriverTestEPaper(t, epaper.Model5in65f, gpio.High)
+	gray := image.NewUniform(color.Gray{Y: 0x80})
+	area := image.Rect(0, 0, 32, 32)
+
+	for _, dither := range []bool{false, true} {
+		e.Dither = dither
+		draw.Draw(e.Display, area, gray, image.Point{}, draw.Src)
+		if err := e.PrintDisplay(); err != nil {
+			t.Fatal(err)
+		}
+
+		img := e.Emulator().Image()
+		inks := map[color.Color]bool{}
+		for j := area.Min.Y; j < area.Max.Y; j++ {
+			for i := area.Min.X; i < area.Max.X; i++ {
+				inks[img.At(i, j)] = true
+			}
+		}
+		if !dither && len(inks) != 1 {
+			t.Fatalf("Expected a single ink without dithering, found %d", len(inks))
+		}
+		if dither && len(inks) < 2 {
+			t.Fatalf("Expected several inks with dithering, found %d", len(inks))
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package epaper
+
+import (
+	"image/color"
+)
+
+// ColorClassifier maps a color of EPaper.Display to the index of the ink used to print it in palette, the colors the
+// panel can show (see Capabilities).
+type ColorClassifier func(c color.Color, palette color.Palette) int
+
+// classify returns the index in palette of the ink used to print c, using the Classifier if any (the closest color
+// otherwise).
+func (e *EPaper) classify(c color.Color, palette color.Palette) int {
+	if e.Classifier == nil {
+		return palette.Index(c)
+	}
+	if i := e.Classifier(c, palette); i >= 0 && i < len(palette) {
+		return i
+	}
+	return palette.Index(c)
+}
+
+// ChromaClassifier returns a ColorClassifier for the panels with a third ink (red or yellow), the last color of their
+// palette. The colors whose chroma (the difference between their largest and smallest components) reaches minChroma
+// are printed with the third ink, the others in black or white depending on whether their luminance is below
+// threshold.
+func ChromaClassifier(minChroma, threshold uint8) ColorClassifier {
+	return func(c color.Color, palette color.Palette) int {
+		r, g, b, _ := c.RGBA()
+		max, min := r, r
+		for _, v := range []uint32{g, b} {
+			if v > max {
+				max = v
+			}
+			if v < min {
+				min = v
+			}
+		}
+		if len(palette) > 2 && (max-min)>>8 >= uint32(minChroma) {
+			return len(palette) - 1
+		}
+		if color.GrayModel.Convert(c).(color.Gray).Y < threshold {
+			return palette.Index(color.Black)
+		}
+		return palette.Index(color.White)
+	}
+}
@@ -0,0 +1,124 @@
+package epaper
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+)
+
+const (
+	// DefaultSPIFrequency is the SPI clock speed used unless configured otherwise.
+	// Waveshare's official libraries use 4 MHz, lower it if the wires are long or of poor quality.
+	DefaultSPIFrequency = 5 * physic.MegaHertz
+
+	// MaxSPIFrequency is the fastest SPI clock supported by the controllers of the panels (for write operations).
+	MaxSPIFrequency = 20 * physic.MegaHertz
+)
+
+// Config contains the settings used to connect to the display. See Open() to set them with options.
+type Config struct {
+	// Names of the pins where DC, CS, RST and BUSY are connected (see gpioreg.ByName).
+	DataCommandPin   string
+	ChipSelectionPin string
+	ResetPin         string
+	BusyPin          string
+
+	// Pins to use instead of looking them up by name (e.g. pins behind an I/O expander, or fakes for tests).
+	// BUSY is configured as an input, detecting both edges when the pin supports it (it is polled otherwise).
+	DataCommand   gpio.PinIO
+	ChipSelection gpio.PinIO
+	Reset         gpio.PinIO
+	Busy          gpio.PinIO
+
+	// Port is the SPI port to use instead of opening SPIPort (e.g. a bit-banged SPI). It is not closed by EPaper.
+	Port spi.Port
+
+	// SPIPort is the name of the SPI port (e.g. "SPI0.0" or "SPI1.0", see spireg.Open). Empty uses the first port.
+	SPIPort string
+
+	// SPIFrequency is the SPI clock speed (DefaultSPIFrequency if zero).
+	SPIFrequency physic.Frequency
+
+	// SPIMode is the clock polarity and phase of the SPI bus (spi.Mode0 to spi.Mode3), with the flags spi.HalfDuplex
+	// (e.g. the panels with a single data line, to read it), spi.NoCS and spi.LSBFirst. The panels use spi.Mode0.
+	SPIMode spi.Mode
+
+	// MaxTxSize is the largest data transfer sent at once (0: the limit of the SPI port). It can not exceed the
+	// limit reported by the port.
+	MaxTxSize int
+
+	// Simulation replaces the pins and the SPI port by fakes; the bytes sent are written to Debug.
+	Simulation bool
+	Debug      io.Writer
+
+	// SleepOnClose puts the display in deep sleep when EPaper.Close() is called (if it is not sleeping already).
+	SleepOnClose bool
+
+	// BusyTimeout is the longest time to wait for the display to finish an operation (DefaultBusyTimeout if zero).
+	BusyTimeout time.Duration
+
+	// Rotation is the orientation of EPaper.Display on the panel.
+	Rotation Rotation
+
+	// Logger receives the commands sent and the time spent waiting for the display (nothing is logged if nil).
+	Logger Logger
+}
+
+// Logger is the interface used to trace the operations of the display. A *log.Logger can be used.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// DefaultConfig returns the settings of the e-Paper HAT module on the first SPI port.
+func DefaultConfig() Config {
+	return Config{
+		DataCommandPin:   DataCommandPin,
+		ChipSelectionPin: ChipSelectionPin,
+		ResetPin:         ResetPin,
+		BusyPin:          BusyPin,
+		SPIFrequency:     DefaultSPIFrequency,
+		SPIMode:          spi.Mode0,
+		SleepOnClose:     true,
+	}
+}
+
+// validate checks the settings that can be verified before connecting to the display.
+func (c *Config) validate() error {
+	if c.SPIFrequency < 0 || c.SPIFrequency > MaxSPIFrequency {
+		return fmt.Errorf("%w: SPI frequency %s is not in the range (0, %s]", ErrInvalidConfig, c.SPIFrequency, MaxSPIFrequency)
+	}
+	if c.SPIMode&^(spi.Mode3|spi.HalfDuplex|spi.NoCS|spi.LSBFirst) != 0 {
+		return fmt.Errorf("%w: SPI mode %s is not supported, use spi.Mode0 to spi.Mode3 and the flags HalfDuplex, NoCS "+
+			"or LSBFirst", ErrInvalidConfig, c.SPIMode)
+	}
+	if c.Rotation < Rotation0 || c.Rotation > Rotation270 {
+		return fmt.Errorf("%w: unknown rotation %d", ErrInvalidConfig, c.Rotation)
+	}
+	if c.MaxTxSize < 0 {
+		return fmt.Errorf("%w: negative MaxTxSize %d", ErrInvalidConfig, c.MaxTxSize)
+	}
+	return nil
+}
+
+// maxTxSize returns the largest transfer to use on connection, checking MaxTxSize against its limits.
+func (c *Config) maxTxSize(connection conn.Conn) (int, error) {
+	limit := 0
+	if l, ok := connection.(conn.Limits); ok {
+		limit = l.MaxTxSize()
+	}
+
+	switch {
+	case c.MaxTxSize == 0 && limit > 0:
+		return limit, nil
+	case c.MaxTxSize == 0:
+		return defaultMaxTxSize, nil
+	case limit > 0 && c.MaxTxSize > limit:
+		return 0, fmt.Errorf("%w: MaxTxSize %d exceeds the limit of the SPI port (%d)", ErrInvalidConfig, c.MaxTxSize, limit)
+	}
+	return c.MaxTxSize, nil
+}
@@ -0,0 +1,79 @@
+package epaper_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mcules/go-epaper-lib"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+)
+
+func TestConfigValidation(t *testing.T) {
+	for name, update := range map[string]func(*epaper.Config){
+		"frequency too high": func(c *epaper.Config) { c.SPIFrequency = 50 * physic.MegaHertz },
+		"negative frequency": func(c *epaper.Config) { c.SPIFrequency = -physic.MegaHertz },
+		"unsupported mode":   func(c *epaper.Config) { c.SPIMode = spi.Mode0 | 0x100 },
+		"negative tx size":   func(c *epaper.Config) { c.MaxTxSize = -1 },
+	} {
+		config := epaper.DefaultConfig()
+		config.Simulation = true
+		update(&config)
+
+		e, err := epaper.NewWithConfig(ModelSim, config)
+		if !errors.Is(err, epaper.ErrInvalidConfig) {
+			t.Fatalf("%s: expected ErrInvalidConfig, found %v", name, err)
+		}
+		if e != nil {
+			t.Fatalf("%s: expected to fail, but Object is not nil", name)
+		}
+	}
+}
+
+func TestConfigSPIModeFlags(t *testing.T) {
+	config := epaper.DefaultConfig()
+	config.Simulation = true
+	config.SPIMode = spi.Mode0 | spi.HalfDuplex
+	if _, err := epaper.NewWithConfig(ModelSim, config); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigMaxTxSize(t *testing.T) {
+	// Create a dummy "epaper" sending at most 16 bytes per transfer.
+	// (to create a real one, use the example source code, this won't work!)
+	debug := new(countingWriter)
+	config := epaper.DefaultConfig()
+	config.SPIPort = "SPI1.0"
+	config.SPIFrequency = 4 * physic.MegaHertz
+	config.MaxTxSize = 16
+	config.Simulation = true
+	config.Debug = debug
+	e, err := epaper.NewWithConfig(ModelSim, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Forcing the BUSY to High to avoid being blocked because of WaitUntilIdle().
+	// Do not do this on real cases!
+	e.Busy.Out(gpio.High)
+
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.ClearScreen(); err != nil {
+		t.Fatal(err)
+	}
+	debug.Reset()
+	debug.writes = 0
+
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The command 0x13, the frame in 3 chunks (16 + 16 + 8 bytes) and the command 0x12.
+	if debug.writes != 5 {
+		t.Fatalf("Expected 5 transfers, found %d", debug.writes)
+	}
+}
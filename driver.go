@@ -0,0 +1,229 @@
+package epaper
+
+import (
+	"context"
+	"image"
+	"image/color"
+
+	"periph.io/x/periph/conn/gpio"
+)
+
+// Bus gives a Driver access to the controller of the panel.
+type Bus interface {
+	// Reset toggles the RST pin of the controller (it can also awaken the device).
+	Reset() error
+
+	// Send writes the command cmd to the controller, followed by its data (if any).
+	// Failures are reported as *CommandError.
+	Send(cmd byte, data []byte) error
+
+	// Read writes the command cmd to the controller, then reads n bytes of data. The data line of the panel must be
+	// wired to be read (e.g. to MISO). Failures are reported as *CommandError.
+	Read(cmd byte, n int) ([]byte, error)
+
+	// WaitUntilIdle blocks while the controller reports it is busy.
+	// It returns ErrBusyTimeout if the controller does not become idle in time, or the error of ctx if it is done first.
+	WaitUntilIdle(ctx context.Context) error
+}
+
+// Controller identifies the family of the controller of a panel, which tells its command set.
+type Controller int
+
+const (
+	// ControllerUC81xx is the family of the IL91874, UC8176 and UC8179 controllers: frames are sent with DTM1/DTM2 (0x10
+	// and 0x13), the waveforms are in the LUT registers (0x20 to 0x24) and BUSY is low while the controller is busy.
+	ControllerUC81xx Controller = iota
+
+	// ControllerSSD16xx is the family of the SSD1680 and SSD1681 controllers: frames are written in the RAM (0x24 and
+	// 0x26), refreshes are started with 0x22/0x20, the waveforms are built-in and BUSY is high while the controller is busy.
+	ControllerSSD16xx
+)
+
+// busyLevel returns the level of BUSY while the controller is busy.
+func (c Controller) busyLevel() gpio.Level {
+	if c == ControllerSSD16xx {
+		return gpio.High
+	}
+	return gpio.Low
+}
+
+// PixelFormat tells how the pixels of a frame are packed, line by line from the top-left corner of the panel.
+type PixelFormat int
+
+const (
+	// Format1bpp packs 8 pixels per byte, the leftmost one in the most significant bit. The bit is set for the second
+	// color of the palette (white) and cleared for the first one (black).
+	Format1bpp PixelFormat = iota
+
+	// Format4bpp packs 2 pixels per byte, the leftmost one in the high nibble. The nibble holds the value of the color
+	// of the pixel (see Capabilities.Values).
+	Format4bpp
+
+	// FormatPlanes packs two planes like Format1bpp, one after the other: in the first one, the bit is cleared for the
+	// black pixels; in the second one, it is cleared for the pixels of the third color of the palette (red or yellow).
+	FormatPlanes
+
+	// FormatBitPlanes packs two planes like Format1bpp, one after the other: the first one holds the high bit of the
+	// index of the color of each pixel in the palette, the second one its low bit (4 colors at most).
+	FormatBitPlanes
+)
+
+// pixelsPerByte returns the number of pixels packed in a byte.
+func (f PixelFormat) pixelsPerByte() int {
+	if f == Format4bpp {
+		return 2
+	}
+	return 8
+}
+
+// stride returns the number of bytes of a line of width pixels (lines are padded to whole bytes).
+func (f PixelFormat) stride(width int) int {
+	n := f.pixelsPerByte()
+	return (width + n - 1) / n
+}
+
+// Capabilities describes what a panel is able to do.
+type Capabilities struct {
+	// Palette contains the colors the panel can show. The contents of EPaper.Display are mapped onto it.
+	Palette color.Palette
+
+	// Controller is the family of the controller of the panel.
+	Controller Controller
+
+	// Format tells how the frames sent to WriteFrame are packed.
+	Format PixelFormat
+
+	// Values contains the value packed in the frames for each color of Palette (Format4bpp only). The index of the
+	// color is packed when it is nil.
+	Values []byte
+
+	// Modes contains the refresh modes supported besides ModeFull, which every panel supports. The driver must
+	// implement ModeDriver for the modes other than ModePartial, and PartialDriver for ModePartial.
+	Modes []RefreshMode
+}
+
+// supports tells if the panel supports the refresh mode m.
+func (c Capabilities) supports(m RefreshMode) bool {
+	if m == ModeFull {
+		return true
+	}
+	for _, mode := range c.Modes {
+		if mode == m {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns the value packed in the frames for the color of index i in the palette.
+func (c Capabilities) value(i int) byte {
+	if c.Values != nil {
+		return c.Values[i]
+	}
+	return byte(i)
+}
+
+// RefreshMode selects how the panel is refreshed, trading quality for speed.
+type RefreshMode int
+
+const (
+	// ModeFull flashes the panel several times, which removes any ghosting. It is the default mode.
+	ModeFull RefreshMode = iota
+
+	// ModeFast uses a shorter waveform, leaving a little ghosting.
+	ModeFast
+
+	// ModePartial only refreshes the region which changed since the last refresh, without flashing the screen. The
+	// refresh is full when the screen contents are unknown, or when the RefreshPolicy requires it.
+	ModePartial
+
+	// ModeGray4 shows 4 levels of gray instead of black and white. The frames are converted to the palette of the
+	// driver in this mode (see Capabilities).
+	ModeGray4
+)
+
+// ModeDriver is implemented by the drivers supporting other refresh modes than ModeFull and ModePartial.
+type ModeDriver interface {
+	Driver
+
+	// SetMode selects the mode of the next full refreshes (never ModePartial). It returns ErrUnsupported if the panel
+	// does not support m.
+	SetMode(m RefreshMode) error
+}
+
+// Driver implements the command sequences of a specific panel controller.
+// EPaper delegates every hardware operation to the Driver of its Model.
+type Driver interface {
+	// Capabilities returns the features supported by the panel.
+	Capabilities() Capabilities
+
+	// Init sends the power-up and configuration sequence.
+	Init(ctx context.Context) error
+
+	// Clear fills the controller memory with white pixels.
+	Clear(ctx context.Context) error
+
+	// WriteFrame uploads a converted frame (see EPaper.PrintDisplay) to the controller memory.
+	WriteFrame(ctx context.Context, frame []byte) error
+
+	// Refresh shows the uploaded frame on the panel.
+	Refresh(ctx context.Context) error
+
+	// Sleep puts the controller in deep sleep. It can only be awaken by a reset.
+	Sleep(ctx context.Context) error
+}
+
+// WaveformDriver is implemented by the drivers able to use a custom Waveform.
+type WaveformDriver interface {
+	Driver
+
+	// SetWaveform replaces the waveform of the next full refreshes, or restores the built-in one if w is nil. w is
+	// valid for the controller of the panel (see Waveform.Validate).
+	SetWaveform(w *Waveform) error
+}
+
+// PartialDriver is implemented by the drivers able to refresh a region of the panel without flashing the whole screen.
+type PartialDriver interface {
+	Driver
+
+	// RefreshRegion uploads window, the pixels inside r packed like a frame, and refreshes r with the partial waveform.
+	// The horizontal bounds of r are multiples of 8.
+	RefreshRegion(ctx context.Context, r image.Rectangle, window []byte) error
+}
+
+// command is a controller command with its data, used to describe fixed sequences.
+type command struct {
+	cmd  byte
+	data []byte
+}
+
+// sendSequence sends each command in order, stopping at the first error.
+func sendSequence(b Bus, cmds []command) error {
+	for _, c := range cmds {
+		if err := b.Send(c.cmd, c.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bus exposes the low-level operations of an EPaper to its Driver.
+type bus struct {
+	e *EPaper
+}
+
+func (b bus) Reset() error {
+	return b.e.reset()
+}
+
+func (b bus) Send(cmd byte, data []byte) error {
+	return b.e.send(cmd, data)
+}
+
+func (b bus) Read(cmd byte, n int) ([]byte, error) {
+	return b.e.read(cmd, n)
+}
+
+func (b bus) WaitUntilIdle(ctx context.Context) error {
+	return b.e.waitUntilIdle(ctx)
+}
@@ -0,0 +1,111 @@
+package epaper_test
+
+import (
+	"bytes"
+	"context"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/mcules/go-epaper-lib"
+	"periph.io/x/periph/conn/gpio"
+)
+
+// fakeDriver records the calls made by EPaper and sends a marker command for each of them.
+type fakeDriver struct {
+	bus   epaper.Bus
+	calls []string
+}
+
+func (d *fakeDriver) Capabilities() epaper.Capabilities {
+	return epaper.Capabilities{Palette: color.Palette{color.Black, color.White}}
+}
+
+func (d *fakeDriver) Init(ctx context.Context) error {
+	d.calls = append(d.calls, "init")
+	return d.bus.Send(0xA0, nil)
+}
+
+func (d *fakeDriver) Clear(ctx context.Context) error {
+	d.calls = append(d.calls, "clear")
+	return d.bus.Send(0xA1, nil)
+}
+
+func (d *fakeDriver) WriteFrame(ctx context.Context, frame []byte) error {
+	d.calls = append(d.calls, "write")
+	return d.bus.Send(0xA2, frame)
+}
+
+func (d *fakeDriver) Refresh(ctx context.Context) error {
+	d.calls = append(d.calls, "refresh")
+	return d.bus.Send(0xA3, nil)
+}
+
+func (d *fakeDriver) Sleep(ctx context.Context) error {
+	d.calls = append(d.calls, "sleep")
+	return d.bus.Send(0xA4, nil)
+}
+
+func TestCustomDriver(t *testing.T) {
+	expectedResult := []byte{0xa0, 0xa1, 0xa3, 0xa2, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xa3, 0xa4}
+
+	var driver *fakeDriver
+	model := epaper.Model{
+		Width:  16,
+		Height: 4,
+		NewDriver: func(b epaper.Bus, m epaper.Model) epaper.Driver {
+			driver = &fakeDriver{bus: b}
+			return driver
+		},
+	}
+
+	// Create a dummy "epaper"
+	// (to create a real one, use the example source code, this won't work!)
+	debug := new(bytes.Buffer)
+	e, err := epaper.NewCustom("", "", "", "", model, true, debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.ClearScreen(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := strings.Join(driver.calls, ","); calls != "init,clear,refresh,write,refresh,sleep" {
+		t.Fatalf("Unexpected calls to the driver: %s", calls)
+	}
+
+	errorMsg := validateByteSlice(debug.Bytes(), expectedResult, "Driver commands")
+	if len(errorMsg) > 0 {
+		t.Fatal(errorMsg)
+	}
+}
+
+// newDriverTestEPaper creates a dummy, initialized, "epaper" of the given model. BUSY is forced to the idle level.
+func newDriverTestEPaper(t *testing.T, model epaper.Model, idle gpio.Level) (*epaper.EPaper, *bytes.Buffer) {
+	// Create a dummy "epaper"
+	// (to create a real one, use the example source code, this won't work!)
+	debug := new(bytes.Buffer)
+	e, err := epaper.NewCustom("", "", "", "", model, true, debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Forcing the BUSY to its idle level to avoid being blocked because of WaitUntilIdle().
+	// Do not do this on real cases!
+	e.Busy.Out(idle)
+
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return e, debug
+}
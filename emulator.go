@@ -0,0 +1,391 @@
+package epaper
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"sync"
+
+	"periph.io/x/periph/conn"
+	"periph.io/x/periph/conn/gpio"
+)
+
+// Emulator implements conn.Conn, decoding the commands sent to the panel to rebuild the image it would show.
+// It reads the DC pin on each transfer to tell commands from data. The tri-color panels (FormatPlanes) show the first
+// RAM as the black plane and the second one as the plane of the third color.
+type Emulator struct {
+	mu         sync.Mutex
+	c          conn.Conn  // Optional connection receiving every transfer too (e.g. to record them)
+	dc         gpio.PinIn // High: Data, Low: Command
+	controller Controller // Command set decoded
+	caps       Capabilities
+	shown      Capabilities // Capabilities when the screen was last refreshed, to decode it
+	cmd        byte         // Last command received
+	args       []byte       // Data received since the last command
+
+	width, height int
+	lineWidth     int
+	oldData       []byte // RAM written by DTM1 (or the red RAM of the SSD1680)
+	newData       []byte // RAM written by DTM2 (or the black RAM of the SSD1680)
+	screen        []byte // What the panel shows (room for two planes)
+	inverted      bool   // The data polarity is inverted (a bit is set for a black pixel)
+	colorInverted bool   // A bit of the color plane is set for a pixel of the third color
+
+	ram    image.Rectangle // SSD1680: RAM window (X in bytes, bounds included)
+	cursor image.Point     // SSD1680: RAM address counters
+	update byte            // SSD1680: display update sequence
+
+	temperature float64 // Measured by the sensors, in degrees Celsius
+
+	poweredOn        bool
+	sleeping         bool
+	refreshes        int
+	partialRefreshes int
+}
+
+// NewEmulator creates an emulator of a width x height panel with a controller of the UC81xx family. Every transfer is
+// also sent to c, unless it is nil.
+func NewEmulator(c conn.Conn, dc gpio.PinIn, width, height int) *Emulator {
+	em := &Emulator{c: c, dc: dc, caps: Capabilities{Palette: color.Palette{color.Black, color.White}}, temperature: 25}
+	em.resize(width, height)
+	return em
+}
+
+// setCapabilities sets the controller and the frame format of the panel emulated. The memory is cleared when the
+// length of the lines changes; otherwise the screen keeps its image until the next refresh.
+func (em *Emulator) setCapabilities(caps Capabilities) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	em.controller = caps.Controller
+	em.caps = caps
+	em.colorInverted = caps.Controller == ControllerSSD16xx
+	if caps.Format.stride(em.width) != em.lineWidth {
+		em.resize(em.width, em.height)
+	}
+}
+
+// Emulator returns the emulator decoding the commands sent to the panel in simulation mode (nil otherwise).
+func (e *EPaper) Emulator() *Emulator {
+	return e.emulator
+}
+
+func (em *Emulator) String() string {
+	return "epaper-emulator"
+}
+
+// Duplex implements conn.Conn.
+func (em *Emulator) Duplex() conn.Duplex {
+	return conn.Half
+}
+
+// MaxTxSize implements conn.Limits, reporting the limit of the connection receiving the transfers (if any).
+func (em *Emulator) MaxTxSize() int {
+	if l, ok := em.c.(conn.Limits); ok {
+		return l.MaxTxSize()
+	}
+	return 0
+}
+
+// Tx implements conn.Conn. The emulator answers the reads itself: only the data written is sent to the connection.
+func (em *Emulator) Tx(w, r []byte) error {
+	if em.c != nil && len(w) > 0 {
+		if err := em.c.Tx(w, nil); err != nil {
+			return err
+		}
+	}
+
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	if em.sleeping {
+		// Only a reset awakes the controller from deep sleep.
+		return nil
+	}
+	if len(r) > 0 {
+		em.read(r)
+	}
+	isData := em.dc.Read() == gpio.High
+	for _, b := range w {
+		if isData {
+			em.data(b)
+		} else {
+			em.command(b)
+		}
+	}
+	return nil
+}
+
+// Reset emulates a hardware reset of the controller, waking it up from deep sleep. The screen keeps its image.
+func (em *Emulator) Reset() {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	em.sleeping = false
+	em.poweredOn = false
+	em.inverted = false
+	em.colorInverted = em.controller == ControllerSSD16xx
+	em.cmd = 0
+	em.args = em.args[:0]
+	em.ram = image.Rect(0, 0, em.lineWidth-1, em.height-1)
+	em.cursor = image.Point{}
+}
+
+// ResetPin wraps the RST pin p, so the emulator is reset each time the pin is driven low.
+func (em *Emulator) ResetPin(p gpio.PinIO) gpio.PinIO {
+	return &emulatorResetPin{PinIO: p, em: em}
+}
+
+// Image returns what the panel currently shows.
+func (em *Emulator) Image() image.Image {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	switch em.shown.Format {
+	case Format4bpp:
+		return em.image4bpp()
+	case FormatPlanes, FormatBitPlanes:
+		return em.imagePlanes()
+	}
+
+	img := image.NewGray(image.Rect(0, 0, em.width, em.height))
+	for j := 0; j < em.height; j++ {
+		for i := 0; i < em.width; i++ {
+			white := em.screen[j*em.lineWidth+i/8]&(0x80>>uint(i%8)) != 0
+			if white != em.inverted {
+				img.SetGray(i, j, color.Gray{Y: 0xff})
+			}
+		}
+	}
+	return img
+}
+
+// image4bpp decodes a screen packed with 2 pixels per byte. Unknown values are shown white.
+func (em *Emulator) image4bpp() image.Image {
+	colors := map[byte]color.Color{}
+	for i, c := range em.shown.Palette {
+		colors[em.shown.value(i)] = c
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, em.width, em.height))
+	for j := 0; j < em.height; j++ {
+		for i := 0; i < em.width; i++ {
+			value := em.screen[j*em.lineWidth+i/2]
+			if i%2 == 0 {
+				value >>= 4
+			}
+			c, ok := colors[value&0x0f]
+			if !ok {
+				c = color.White
+			}
+			img.Set(i, j, c)
+		}
+	}
+	return img
+}
+
+// imagePlanes decodes a screen made of two planes: a black plane and a plane of the third color, which prevails, or
+// the high and low bits of the indexes in the palette (FormatBitPlanes).
+func (em *Emulator) imagePlanes() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, em.width, em.height))
+	plane := em.lineWidth * em.height
+	for j := 0; j < em.height; j++ {
+		for i := 0; i < em.width; i++ {
+			offset, mask := j*em.lineWidth+i/8, byte(0x80>>uint(i%8))
+			first, second := em.screen[offset]&mask != 0, em.screen[plane+offset]&mask != 0
+			var c color.Color = color.White
+			switch {
+			case em.shown.Format == FormatBitPlanes:
+				index := 0
+				if first {
+					index |= 2
+				}
+				if second {
+					index |= 1
+				}
+				if index < len(em.shown.Palette) {
+					c = em.shown.Palette[index]
+				}
+			case second == em.colorInverted:
+				c = em.shown.Palette[len(em.shown.Palette)-1]
+			case first == em.inverted:
+				c = color.Black
+			}
+			img.Set(i, j, c)
+		}
+	}
+	return img
+}
+
+// show copies the RAMs to the screen: the new data, or both planes for FormatPlanes and FormatBitPlanes.
+func (em *Emulator) show(first, second []byte) {
+	em.shown = em.caps
+	if em.caps.Format != FormatPlanes && em.caps.Format != FormatBitPlanes {
+		copy(em.screen, em.newData)
+		return
+	}
+	copy(em.screen, first)
+	copy(em.screen[len(first):], second)
+}
+
+// SetTemperature sets the temperature measured by the sensors of the panel, in degrees Celsius (25 by default).
+func (em *Emulator) SetTemperature(celsius float64) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	em.temperature = celsius
+}
+
+// read answers the read of the data of the current command: the temperature (see encodeTemperature) after TSC or TSR,
+// zeros otherwise.
+func (em *Emulator) read(r []byte) {
+	for i := range r {
+		r[i] = 0
+	}
+	if em.controller == ControllerUC81xx && (em.cmd == CmdTemperatureSensor || em.cmd == CmdTemperatureRead) {
+		copy(r, encodeTemperature(em.temperature))
+	}
+}
+
+// PoweredOn tells if the panel is powered on (between the POWER ON and POWER OFF commands).
+func (em *Emulator) PoweredOn() bool {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	return em.poweredOn
+}
+
+// Sleeping tells if the panel is in deep sleep.
+func (em *Emulator) Sleeping() bool {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	return em.sleeping
+}
+
+// Refreshes returns the number of full and partial refreshes shown by the panel.
+func (em *Emulator) Refreshes() (full, partial int) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	return em.refreshes, em.partialRefreshes
+}
+
+func (em *Emulator) resize(width, height int) {
+	em.width, em.height = width, height
+	em.lineWidth = em.caps.Format.stride(width)
+	size := em.lineWidth * height
+	em.oldData = bytes.Repeat([]byte{0xFF}, size)
+	em.newData = bytes.Repeat([]byte{0xFF}, size)
+	em.screen = bytes.Repeat([]byte{0xFF}, 2*size)
+	em.shown = em.caps
+	em.ram = image.Rect(0, 0, em.lineWidth-1, em.height-1)
+}
+
+func (em *Emulator) command(c byte) {
+	em.cmd = c
+	em.args = em.args[:0]
+	if em.controller == ControllerSSD16xx {
+		em.ssdCommand(c)
+		return
+	}
+
+	switch c {
+	case CmdPowerOn:
+		em.poweredOn = true
+	case CmdPowerOff:
+		em.poweredOn = false
+	case CmdDisplayRefresh:
+		// The panel is not updated unless it is powered on.
+		if em.poweredOn {
+			em.show(em.oldData, em.newData)
+			em.refreshes++
+		}
+	}
+}
+
+func (em *Emulator) data(d byte) {
+	em.args = append(em.args, d)
+	n := len(em.args)
+	if em.controller == ControllerSSD16xx {
+		em.ssdData(d, n)
+		return
+	}
+
+	switch em.cmd {
+	case CmdDataStartTransimission1:
+		// The controllers taking 4 bits per pixel only have one frame buffer.
+		ram := em.oldData
+		if em.caps.Format == Format4bpp {
+			ram = em.newData
+		}
+		if n <= len(ram) {
+			ram[n-1] = d
+		}
+	case CmdDataStartTransimission2:
+		// 0x13 is another command for the controllers taking 4 bits per pixel.
+		if em.caps.Format != Format4bpp && n <= len(em.newData) {
+			em.newData[n-1] = d
+		}
+	case CmdPartialDataStartTransimission2:
+		// 8 bytes for the window, then its data.
+		if n > 8 {
+			r := em.window()
+			w := r.Dx() / 8
+			if w > 0 {
+				i := n - 9
+				x, y := r.Min.X/8+i%w, r.Min.Y+i/w
+				if x < em.lineWidth && y < em.height {
+					em.newData[y*em.lineWidth+x] = d
+				}
+			}
+		}
+	case CmdPartialDisplayRefresh:
+		// The single byte version is only a configuration.
+		if n == 8 && em.poweredOn {
+			r := em.window().Intersect(image.Rect(0, 0, em.lineWidth*8, em.height))
+			for j := r.Min.Y; j < r.Max.Y; j++ {
+				start, end := j*em.lineWidth+r.Min.X/8, j*em.lineWidth+r.Max.X/8
+				copy(em.screen[start:end], em.newData[start:end])
+			}
+			em.shown = em.caps
+			em.partialRefreshes++
+		}
+	case CmdVcomDataIntervalSet:
+		if n == 1 {
+			em.inverted = d&0x01 == 0
+			em.colorInverted = d&0x02 == 0
+		}
+	case CmdTconResolution:
+		if n == 4 {
+			// The RAM is kept when the resolution does not change.
+			width, height := int(em.args[0])<<8|int(em.args[1]), int(em.args[2])<<8|int(em.args[3])
+			if width != em.width || height != em.height {
+				em.resize(width, height)
+			}
+		}
+	case CMdDeepSleep:
+		if d == 0xA5 {
+			em.sleeping = true
+			em.poweredOn = false
+		}
+	}
+}
+
+// window decodes the region sent as the first 8 bytes of the partial refresh commands.
+func (em *Emulator) window() image.Rectangle {
+	a := em.args
+	x, y := int(a[0])<<8|int(a[1]&0xf8), int(a[2])<<8|int(a[3])
+	w, l := int(a[4])<<8|int(a[5]&0xf8), int(a[6])<<8|int(a[7])
+	return image.Rect(x, y, x+w, y+l)
+}
+
+// emulatorResetPin resets the emulator when the RST pin goes low.
+type emulatorResetPin struct {
+	gpio.PinIO
+	em *Emulator
+}
+
+func (p *emulatorResetPin) Out(l gpio.Level) error {
+	if err := p.PinIO.Out(l); err != nil {
+		return err
+	}
+	if l == gpio.Low {
+		p.em.Reset()
+	}
+	return nil
+}
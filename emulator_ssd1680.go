@@ -0,0 +1,70 @@
+package epaper
+
+import "image"
+
+// ssdCommand decodes the commands of the SSD1680 family.
+func (em *Emulator) ssdCommand(c byte) {
+	switch c {
+	case ssdSoftReset:
+		em.ram = image.Rect(0, 0, em.lineWidth-1, em.height-1)
+		em.cursor = image.Point{}
+	case ssdMasterActivation:
+		if em.update&ssdDisplayBit == 0 {
+			return
+		}
+		em.show(em.newData, em.oldData)
+		if em.update&ssdDisplayMode2Bit != 0 {
+			// The new image becomes the reference of the next partial update.
+			copy(em.oldData, em.newData)
+			em.partialRefreshes++
+		} else {
+			em.refreshes++
+		}
+	}
+}
+
+// ssdData decodes the n-th byte d of data of the current SSD1680 command.
+func (em *Emulator) ssdData(d byte, n int) {
+	a := em.args
+	switch em.cmd {
+	case ssdSetRAMXAddress:
+		if n == 2 {
+			em.ram.Min.X, em.ram.Max.X = int(a[0]), int(a[1])
+		}
+	case ssdSetRAMYAddress:
+		if n == 4 {
+			em.ram.Min.Y, em.ram.Max.Y = int(a[0])|int(a[1])<<8, int(a[2])|int(a[3])<<8
+		}
+	case ssdSetRAMXAddressCounter:
+		if n == 1 {
+			em.cursor.X = int(d)
+		}
+	case ssdSetRAMYAddressCounter:
+		if n == 2 {
+			em.cursor.Y = int(a[0]) | int(a[1])<<8
+		}
+	case ssdWriteRAMBlack:
+		em.ssdWrite(em.newData, d)
+	case ssdWriteRAMRed:
+		em.ssdWrite(em.oldData, d)
+	case ssdUpdateControl2:
+		em.update = d
+	case ssdDeepSleep:
+		if d != 0 {
+			em.sleeping = true
+		}
+	}
+}
+
+// ssdWrite writes d in ram at the address counters, then moves them inside the RAM window (X then Y increment).
+func (em *Emulator) ssdWrite(ram []byte, d byte) {
+	x, y := em.cursor.X, em.cursor.Y
+	if x < em.lineWidth && y < em.height {
+		ram[y*em.lineWidth+x] = d
+	}
+	em.cursor.X++
+	if em.cursor.X > em.ram.Max.X {
+		em.cursor.X = em.ram.Min.X
+		em.cursor.Y++
+	}
+}
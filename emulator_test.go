@@ -0,0 +1,96 @@
+package epaper_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/mcules/go-epaper-lib"
+	"periph.io/x/periph/conn/gpio"
+)
+
+// countBlack returns the number of black pixels inside r.
+func countBlack(img image.Image, r image.Rectangle) int {
+	count := 0
+	for j := r.Min.Y; j < r.Max.Y; j++ {
+		for i := r.Min.X; i < r.Max.X; i++ {
+			if color.GrayModel.Convert(img.At(i, j)).(color.Gray).Y < 0x80 {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func TestEmulator(t *testing.T) {
+	// Create a dummy "epaper"
+	// (to create a real one, use the example source code, this won't work!)
+	debug := new(bytes.Buffer)
+	e, err := epaper.NewCustom("", "", "", "", epaper.Model{Width: 16, Height: 20}, true, debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	em := e.Emulator()
+	if em == nil {
+		t.Fatal("Expected an emulator in simulation mode")
+	}
+
+	// Forcing the BUSY to High to avoid being blocked because of WaitUntilIdle().
+	// Do not do this on real cases!
+	e.Busy.Out(gpio.High)
+
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if !em.PoweredOn() {
+		t.Fatal("Expected the panel to be powered on after Init")
+	}
+	if err := e.ClearScreen(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Full refresh.
+	draw.Draw(e.Display, image.Rect(0, 0, 8, 4), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+	if n := countBlack(em.Image(), em.Image().Bounds()); n != 32 {
+		t.Fatalf("Expected 32 black pixels on screen, found %d", n)
+	}
+
+	// Partial refresh: only the region is updated on screen.
+	draw.Draw(e.Display, image.Rect(8, 10, 16, 12), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	draw.Draw(e.Display, image.Rect(0, 0, 8, 4), image.NewUniform(color.White), image.Point{}, draw.Src)
+	if err := e.PrintRegion(image.Rect(8, 10, 16, 12)); err != nil {
+		t.Fatal(err)
+	}
+	if n := countBlack(em.Image(), image.Rect(8, 10, 16, 12)); n != 16 {
+		t.Fatalf("Expected 16 black pixels in the region, found %d", n)
+	}
+	if n := countBlack(em.Image(), image.Rect(0, 0, 8, 4)); n != 32 {
+		t.Fatalf("Expected the outside of the region to be unchanged, found %d black pixels", n)
+	}
+	if full, partial := em.Refreshes(); full != 2 || partial != 1 {
+		t.Fatalf("Expected 2 full and 1 partial refreshes, found %d and %d", full, partial)
+	}
+
+	// Deep sleep: the image stays, commands are ignored until a reset.
+	if err := e.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	if !em.Sleeping() || em.PoweredOn() {
+		t.Fatal("Expected the panel to be sleeping and powered off")
+	}
+	if n := countBlack(em.Image(), em.Image().Bounds()); n != 48 {
+		t.Fatalf("Expected the image to stay on screen while sleeping, found %d black pixels", n)
+	}
+
+	if err := e.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if em.Sleeping() {
+		t.Fatal("Expected the panel to wake up after a reset")
+	}
+}
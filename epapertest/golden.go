@@ -0,0 +1,81 @@
+// Package epapertest contains helpers to test code printing on an e-paper display in simulation mode.
+package epapertest
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mcules/go-epaper-lib"
+)
+
+// Update makes AssertGolden rewrite the golden files with the current snapshots instead of comparing them. It is set
+// when the environment variable EPAPERTEST_UPDATE is not empty, e.g. EPAPERTEST_UPDATE=1 go test ./...
+var Update = os.Getenv("EPAPERTEST_UPDATE") != ""
+
+// Diff compares two images pixel by pixel. It returns the number of different pixels and a visual diff:
+// different pixels are red, the others are a faded copy of want.
+func Diff(got, want image.Image) (int, *image.RGBA) {
+	bounds := got.Bounds().Union(want.Bounds())
+	diff := image.NewRGBA(bounds)
+	count := 0
+	for j := bounds.Min.Y; j < bounds.Max.Y; j++ {
+		for i := bounds.Min.X; i < bounds.Max.X; i++ {
+			p := image.Point{X: i, Y: j}
+			g := color.GrayModel.Convert(got.At(i, j)).(color.Gray)
+			w := color.GrayModel.Convert(want.At(i, j)).(color.Gray)
+			if !p.In(got.Bounds()) || !p.In(want.Bounds()) || g != w {
+				diff.SetRGBA(i, j, color.RGBA{R: 0xff, A: 0xff})
+				count++
+				continue
+			}
+			faded := 0xc0 + w.Y/4
+			diff.SetRGBA(i, j, color.RGBA{R: faded, G: faded, B: faded, A: 0xff})
+		}
+	}
+	return count, diff
+}
+
+// AssertGolden compares img with the golden PNG file. On failure, the snapshot and a visual diff (see Diff) are
+// written next to it, as <name>.got.png and <name>.diff.png. The golden file is rewritten instead when Update is set.
+func AssertGolden(t testing.TB, img image.Image, golden string) {
+	t.Helper()
+
+	if img == nil {
+		t.Fatal("No snapshot to compare: is the display in simulation mode?")
+	}
+
+	if Update {
+		if err := epaper.SavePNG(img, golden); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	f, err := os.Open(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	want, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, diff := Diff(img, want)
+	if count == 0 {
+		return
+	}
+
+	base := strings.TrimSuffix(golden, ".png")
+	if err := epaper.SavePNG(img, base+".got.png"); err != nil {
+		t.Error(err)
+	}
+	if err := epaper.SavePNG(diff, base+".diff.png"); err != nil {
+		t.Error(err)
+	}
+	t.Fatalf("%d pixels differ from %s, see %s.diff.png", count, golden, base)
+}
@@ -0,0 +1,35 @@
+package epapertest_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/mcules/go-epaper-lib/epapertest"
+)
+
+func TestDiff(t *testing.T) {
+	want := image.NewGray(image.Rect(0, 0, 8, 4))
+	draw.Draw(want, want.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	got := image.NewGray(want.Bounds())
+	draw.Draw(got, got.Bounds(), want, image.Point{}, draw.Src)
+	got.SetGray(1, 2, color.Gray{})
+	got.SetGray(7, 3, color.Gray{})
+
+	count, diff := epapertest.Diff(got, want)
+	if count != 2 {
+		t.Fatalf("Expected 2 different pixels, found %d", count)
+	}
+	if diff.RGBAAt(1, 2) != (color.RGBA{R: 0xff, A: 0xff}) {
+		t.Fatalf("Expected a red pixel on the diff, found %v", diff.RGBAAt(1, 2))
+	}
+	if diff.RGBAAt(0, 0) == (color.RGBA{R: 0xff, A: 0xff}) {
+		t.Fatal("Expected identical pixels not to be red")
+	}
+
+	if count, _ := epapertest.Diff(want, want); count != 0 {
+		t.Fatalf("Expected no difference, found %d", count)
+	}
+}
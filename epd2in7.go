@@ -0,0 +1,249 @@
+package epaper
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"time"
+)
+
+// epd2in7 drives the 2.7 inches black-and-white panel (IL91874 controller).
+type epd2in7 struct {
+	bus     Bus
+	model   Model
+	mode    RefreshMode
+	partial bool // The partial refresh LUTs are loaded
+	gray    bool // The grayscale settings and LUTs are loaded
+	stale   bool // The full refresh LUTs changed since they were loaded
+
+	waveform *Waveform // Custom waveform of the full refreshes (nil: waveform2in7)
+}
+
+// gray4Palette contains the levels of ModeGray4, by index of the pair of bits sent in DTM1 and DTM2.
+var gray4Palette = color.Palette{color.Black, color.Gray{Y: 0x55}, color.Gray{Y: 0xaa}, color.White}
+
+// newEpd2in7 creates the driver for the 2.7 inches black-and-white panel.
+func newEpd2in7(b Bus, m Model) Driver {
+	return &epd2in7{bus: b, model: m}
+}
+
+func (d *epd2in7) Capabilities() Capabilities {
+	if d.mode == ModeGray4 {
+		return Capabilities{
+			Palette: gray4Palette,
+			Format:  FormatBitPlanes,
+			Modes:   []RefreshMode{ModePartial, ModeGray4},
+		}
+	}
+	return Capabilities{
+		Palette: color.Palette{color.Black, color.White},
+		Modes:   []RefreshMode{ModePartial, ModeGray4},
+	}
+}
+
+func (d *epd2in7) Init(ctx context.Context) error {
+	if err := d.bus.Reset(); err != nil {
+		return err
+	}
+
+	err := sendSequence(d.bus, []command{
+		{CmdPowerSetting, []byte{0x03, 0x00, 0x2b, 0x2b, 0x09}},
+		{CmdBoosterSoftStart, []byte{0x07, 0x07, 0x17}},
+
+		// Power optimizations (new)
+		{CmdPowerOptimization, []byte{0x60, 0xa5}},
+		{CmdPowerOptimization, []byte{0x89, 0xa5}},
+		{CmdPowerOptimization, []byte{0x90, 0x00}},
+		{CmdPowerOptimization, []byte{0x93, 0x2a}},
+		{CmdPowerOptimization, []byte{0xa0, 0xa5}},
+		{CmdPowerOptimization, []byte{0xa1, 0x00}},
+		{CmdPowerOptimization, []byte{0x73, 0x41}},
+
+		{CmdPartialDisplayRefresh, []byte{0x00}},
+
+		{CmdPowerOn, nil},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := d.bus.WaitUntilIdle(ctx); err != nil {
+		return err
+	}
+
+	err = sendSequence(d.bus, []command{
+		{CmdPanelSetting, []byte{0xaf}},
+
+		{CmdPllControl, []byte{0x3a}}, // 3A 100Hz, 29 150Hz, 39 200Hz, 31 171Hz
+
+		{CmdVcmDcSetting, []byte{0x12}},
+	})
+	if err != nil {
+		return err
+	}
+
+	d.gray = false
+	return d.loadLuts(false)
+}
+
+func (d *epd2in7) SetMode(m RefreshMode) error {
+	if m != ModeFull && m != ModeGray4 {
+		return ErrUnsupported
+	}
+	d.mode = m
+	return nil
+}
+
+// loadLuts sends either the full or the partial refresh LUTs to the controller, leaving the grayscale mode if needed.
+func (d *epd2in7) loadLuts(partial bool) error {
+	if d.gray {
+		err := sendSequence(d.bus, []command{
+			{CmdPanelSetting, []byte{0xaf}},
+			{CmdPllControl, []byte{0x3a}},
+			{CmdTconResolution, d.resolution()},
+			{CmdVcomDataIntervalSet, []byte{0xd7}}, // Reset value of the controller
+		})
+		if err != nil {
+			return err
+		}
+		d.gray = false
+	}
+
+	luts := waveform2in7.commands()
+	if d.waveform != nil {
+		luts = d.waveform.commands()
+	}
+	if partial {
+		luts = waveform2in7Partial.commands()
+	}
+
+	if err := sendSequence(d.bus, luts); err != nil {
+		return err
+	}
+	d.partial, d.stale = partial, partial && d.stale
+	return nil
+}
+
+func (d *epd2in7) SetWaveform(w *Waveform) error {
+	d.waveform, d.stale = w, true
+	return nil
+}
+
+// loadGrayLuts sends the grayscale settings and LUTs to the controller.
+func (d *epd2in7) loadGrayLuts() error {
+	err := sendSequence(d.bus, []command{
+		{CmdPanelSetting, []byte{0xbf}}, // KW mode: the LUT is selected by the bits in DTM1 and DTM2
+		{CmdPllControl, []byte{0x90}},
+		{CmdTconResolution, d.resolution()},
+		{CmdVcomDataIntervalSet, []byte{0x97}},
+	})
+	if err == nil {
+		err = sendSequence(d.bus, append(waveform2in7Gray.commands(), command{CmdLutRed0, waveform2in7Gray.WW}))
+	}
+	if err != nil {
+		return err
+	}
+	d.gray, d.partial = true, false
+	return nil
+}
+
+// resolution returns the data of the TRES command for the panel.
+func (d *epd2in7) resolution() []byte {
+	w, h := d.model.Width, d.model.Height
+	return []byte{byte(w >> 8), byte(w), byte(h >> 8), byte(h)}
+}
+
+func (d *epd2in7) Temperature(ctx context.Context, source TemperatureSource) (float64, error) {
+	return ucTemperature(ctx, d.bus, source)
+}
+
+func (d *epd2in7) Clear(ctx context.Context) error {
+	data := bytes.Repeat([]byte{0xFF}, (d.model.Width+7)/8*d.model.Height) // Each byte contains 8 pixels, lines are padded
+
+	if err := d.bus.Send(CmdDataStartTransimission1, data); err != nil {
+		return err
+	}
+	return d.bus.Send(d.startTransmission(), data)
+}
+
+func (d *epd2in7) WriteFrame(ctx context.Context, frame []byte) error {
+	if d.mode == ModeGray4 {
+		// The high bits of the levels in DTM1, the low ones in DTM2.
+		n := len(frame) / 2
+		if err := d.bus.Send(CmdDataStartTransimission1, frame[:n]); err != nil {
+			return err
+		}
+		return d.bus.Send(d.startTransmission(), frame[n:])
+	}
+	if d.gray {
+		// DTM1 still holds the high bits of the last grayscale frame.
+		if err := d.bus.Send(CmdDataStartTransimission1, bytes.Repeat([]byte{0xFF}, len(frame))); err != nil {
+			return err
+		}
+	}
+
+	// This command is required before sending data to print on screen.
+	return d.bus.Send(d.startTransmission(), frame)
+}
+
+func (d *epd2in7) Refresh(ctx context.Context) error {
+	var err error
+	switch {
+	case d.mode == ModeGray4 && !d.gray:
+		err = d.loadGrayLuts()
+	case d.mode != ModeGray4 && (d.partial || d.gray || d.stale):
+		err = d.loadLuts(false)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := d.bus.Send(CmdDisplayRefresh, nil); err != nil {
+		return err
+	}
+	time.Sleep(100 * time.Millisecond)
+	return d.bus.WaitUntilIdle(ctx)
+}
+
+func (d *epd2in7) RefreshRegion(ctx context.Context, r image.Rectangle, window []byte) error {
+	if !d.partial {
+		if err := d.loadLuts(true); err != nil {
+			return err
+		}
+	}
+
+	// X and width are multiples of 8, so their 3 lower bits are ignored by the controller.
+	area := []byte{
+		byte(r.Min.X >> 8), byte(r.Min.X & 0xf8),
+		byte(r.Min.Y >> 8), byte(r.Min.Y),
+		byte(r.Dx() >> 8), byte(r.Dx() & 0xf8),
+		byte(r.Dy() >> 8), byte(r.Dy()),
+	}
+
+	if err := d.bus.Send(CmdPartialDataStartTransimission2, append(area, window...)); err != nil {
+		return err
+	}
+	if err := d.bus.Send(CmdPartialDisplayRefresh, area); err != nil {
+		return err
+	}
+	return d.bus.WaitUntilIdle(ctx)
+}
+
+func (d *epd2in7) Sleep(ctx context.Context) error {
+	if err := d.bus.Send(CmdPowerOff, nil); err != nil {
+		return err
+	}
+	if err := d.bus.WaitUntilIdle(ctx); err != nil {
+		return err
+	}
+	return d.bus.Send(CMdDeepSleep, []byte{0xA5})
+}
+
+// startTransmission returns the command used to upload the new frame (DTM2 unless the model says otherwise).
+func (d *epd2in7) startTransmission() byte {
+	if d.model.StartTransmission == 0 {
+		return CmdDataStartTransimission2
+	}
+	return d.model.StartTransmission
+}
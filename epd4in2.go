@@ -0,0 +1,116 @@
+package epaper
+
+import (
+	"bytes"
+	"context"
+	"image/color"
+	"time"
+)
+
+// epd4in2 drives the 4.2 inches black-and-white panel (UC8176 controller).
+type epd4in2 struct {
+	bus      Bus
+	model    Model
+	waveform *Waveform // Custom waveform (nil: waveform4in2)
+	stale    bool      // The LUTs changed since they were loaded
+}
+
+// newEpd4in2 creates the driver for the 4.2 inches black-and-white panel.
+func newEpd4in2(b Bus, m Model) Driver {
+	return &epd4in2{bus: b, model: m}
+}
+
+func (d *epd4in2) Capabilities() Capabilities {
+	return Capabilities{
+		Palette: color.Palette{color.Black, color.White},
+	}
+}
+
+func (d *epd4in2) Init(ctx context.Context) error {
+	if err := d.bus.Reset(); err != nil {
+		return err
+	}
+
+	err := sendSequence(d.bus, []command{
+		{CmdPowerSetting, []byte{0x03, 0x00, 0x2b, 0x2b}},
+		{CmdBoosterSoftStart, []byte{0x17, 0x17, 0x17}},
+		{CmdPowerOn, nil},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := d.bus.WaitUntilIdle(ctx); err != nil {
+		return err
+	}
+
+	w, h := d.model.Width, d.model.Height
+	err = sendSequence(d.bus, []command{
+		{CmdPanelSetting, []byte{0xbf, 0x0d}}, // LUTs from the registers
+		{CmdPllControl, []byte{0x3c}},
+		{CmdTconResolution, []byte{byte(w >> 8), byte(w), byte(h >> 8), byte(h)}},
+		{CmdVcmDcSetting, []byte{0x28}},
+		{CmdVcomDataIntervalSet, []byte{0x97}},
+	})
+	if err != nil {
+		return err
+	}
+	return d.loadLuts()
+}
+
+// loadLuts sends the LUTs of the waveform to the controller.
+func (d *epd4in2) loadLuts() error {
+	w := &waveform4in2
+	if d.waveform != nil {
+		w = d.waveform
+	}
+	if err := sendSequence(d.bus, w.commands()); err != nil {
+		return err
+	}
+	d.stale = false
+	return nil
+}
+
+func (d *epd4in2) SetWaveform(w *Waveform) error {
+	d.waveform, d.stale = w, true
+	return nil
+}
+
+func (d *epd4in2) Temperature(ctx context.Context, source TemperatureSource) (float64, error) {
+	return ucTemperature(ctx, d.bus, source)
+}
+
+func (d *epd4in2) Clear(ctx context.Context) error {
+	data := bytes.Repeat([]byte{0xFF}, (d.model.Width+7)/8*d.model.Height)
+	if err := d.bus.Send(CmdDataStartTransimission1, data); err != nil {
+		return err
+	}
+	return d.bus.Send(CmdDataStartTransimission2, data)
+}
+
+func (d *epd4in2) WriteFrame(ctx context.Context, frame []byte) error {
+	return d.bus.Send(CmdDataStartTransimission2, frame)
+}
+
+func (d *epd4in2) Refresh(ctx context.Context) error {
+	if d.stale {
+		if err := d.loadLuts(); err != nil {
+			return err
+		}
+	}
+	if err := d.bus.Send(CmdDisplayRefresh, nil); err != nil {
+		return err
+	}
+	time.Sleep(100 * time.Millisecond)
+	return d.bus.WaitUntilIdle(ctx)
+}
+
+func (d *epd4in2) Sleep(ctx context.Context) error {
+	if err := d.bus.Send(CmdPowerOff, nil); err != nil {
+		return err
+	}
+	if err := d.bus.WaitUntilIdle(ctx); err != nil {
+		return err
+	}
+	return d.bus.Send(CMdDeepSleep, []byte{0xA5})
+}
@@ -0,0 +1,166 @@
+package epaper_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"testing"
+
+	"github.com/mcules/go-epaper-lib"
+	"periph.io/x/periph/conn/gpio"
+)
+
+// recordTrace initializes a dummy "epaper" of the given model, clears it, prints a black square and puts it to sleep.
+// It returns the commands sent, as logged, the bytes written by Init (commands and data) and what the panel shows
+// before sleeping.
+func recordTrace(t *testing.T, model epaper.Model) ([]string, []byte, image.Image) {
+	// Create a dummy "epaper"
+	// (to create a real one, use the example source code, this won't work!)
+	logger := &testLogger{}
+	debug := new(bytes.Buffer)
+	e, err := epaper.Open(model, epaper.WithSimulation(debug), epaper.WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Forcing the BUSY to High to avoid being blocked because of WaitUntilIdle().
+	// Do not do this on real cases!
+	e.Busy.Out(gpio.High)
+
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	initData := append([]byte(nil), debug.Bytes()...)
+	if err := e.ClearScreen(); err != nil {
+		t.Fatal(err)
+	}
+	draw.Draw(e.Display, image.Rect(0, 0, 16, 16), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+	screen := e.Snapshot()
+	if err := e.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+
+	trace := []string{}
+	for _, line := range logger.lines {
+		if strings.HasPrefix(line, "epaper: command") {
+			trace = append(trace, line)
+		}
+	}
+	return trace, initData, screen
+}
+
+// validateTrace compares the commands sent with the expected ones.
+func validateTrace(t *testing.T, trace, expected []string) {
+	if len(trace) != len(expected) {
+		t.Fatalf("Expected %d commands, found %d:\n%s", len(expected), len(trace), strings.Join(trace, "\n"))
+	}
+	for i := range expected {
+		if trace[i] != expected[i] {
+			t.Fatalf("Command %d: expected %q, found %q", i, expected[i], trace[i])
+		}
+	}
+}
+
+// validateInit compares the bytes written by Init with the expected ones.
+func validateInit(t *testing.T, initData, expected []byte) {
+	if !bytes.Equal(initData, expected) {
+		t.Fatalf("Expected Init to write\n%x\nfound\n%x", expected, initData)
+	}
+}
+
+func TestEpd4in2Trace(t *testing.T) {
+	trace, initData, screen := recordTrace(t, epaper.Model4in2)
+	validateTrace(t, trace, []string{
+		// Init
+		"epaper: command 0x01 with 4 bytes of data",
+		"epaper: command 0x06 with 3 bytes of data",
+		"epaper: command 0x04 with 0 bytes of data",
+		"epaper: command 0x00 with 2 bytes of data",
+		"epaper: command 0x30 with 1 bytes of data",
+		"epaper: command 0x61 with 4 bytes of data",
+		"epaper: command 0x82 with 1 bytes of data",
+		"epaper: command 0x50 with 1 bytes of data",
+		"epaper: command 0x20 with 44 bytes of data",
+		"epaper: command 0x21 with 42 bytes of data",
+		"epaper: command 0x22 with 42 bytes of data",
+		"epaper: command 0x23 with 42 bytes of data",
+		"epaper: command 0x24 with 42 bytes of data",
+		// ClearScreen
+		"epaper: command 0x10 with 15000 bytes of data",
+		"epaper: command 0x13 with 15000 bytes of data",
+		"epaper: command 0x12 with 0 bytes of data",
+		// PrintDisplay
+		"epaper: command 0x13 with 15000 bytes of data",
+		"epaper: command 0x12 with 0 bytes of data",
+		// Sleep
+		"epaper: command 0x02 with 0 bytes of data",
+		"epaper: command 0x07 with 1 bytes of data",
+	})
+
+	// The settings of the Waveshare examples, then the LUTs.
+	lut := epaper.Waveform4in2()
+	expected := []byte{
+		0x01, 0x03, 0x00, 0x2b, 0x2b, // Power setting
+		0x06, 0x17, 0x17, 0x17, // Booster soft start
+		0x04,             // Power on
+		0x00, 0xbf, 0x0d, // Panel setting
+		0x30, 0x3c, // PLL
+		0x61, 0x01, 0x90, 0x01, 0x2c, // Resolution: 400x300
+		0x82, 0x28, // VCM DC
+		0x50, 0x97, // VCOM and data interval
+	}
+	for i, data := range [][]byte{lut.VCOM, lut.WW, lut.BW, lut.WB, lut.BB} {
+		expected = append(append(expected, byte(0x20+i)), data...)
+	}
+	validateInit(t, initData, expected)
+
+	if n := countBlack(screen, screen.Bounds()); n != 256 {
+		t.Fatalf("Expected 256 black pixels on screen, found %d", n)
+	}
+}
+
+func TestEpd5in83V2Trace(t *testing.T) {
+	trace, initData, screen := recordTrace(t, epaper.Model5in83V2)
+	validateTrace(t, trace, []string{
+		// Init
+		"epaper: command 0x01 with 4 bytes of data",
+		"epaper: command 0x04 with 0 bytes of data",
+		"epaper: command 0x00 with 1 bytes of data",
+		"epaper: command 0x61 with 4 bytes of data",
+		"epaper: command 0x15 with 1 bytes of data",
+		"epaper: command 0x50 with 2 bytes of data",
+		"epaper: command 0x60 with 1 bytes of data",
+		// ClearScreen
+		"epaper: command 0x10 with 38880 bytes of data",
+		"epaper: command 0x13 with 38880 bytes of data",
+		"epaper: command 0x12 with 0 bytes of data",
+		// PrintDisplay
+		"epaper: command 0x13 with 38880 bytes of data",
+		"epaper: command 0x12 with 0 bytes of data",
+		// Sleep
+		"epaper: command 0x02 with 0 bytes of data",
+		"epaper: command 0x07 with 1 bytes of data",
+	})
+
+	validateInit(t, initData, []byte{
+		0x01, 0x07, 0x07, 0x3f, 0x3f, // Power setting
+		0x04,       // Power on
+		0x00, 0x1f, // Panel setting
+		0x61, 0x02, 0x88, 0x01, 0xe0, // Resolution: 648x480
+		0x15, 0x00, // Dual SPI off
+		0x50, 0x10, 0x07, // VCOM and data interval
+		0x60, 0x22, // TCON
+	})
+
+	if b := screen.Bounds(); b != image.Rect(0, 0, 648, 480) {
+		t.Fatalf("Expected a 648x480 screen, found %v", b)
+	}
+	if n := countBlack(screen, screen.Bounds()); n != 256 {
+		t.Fatalf("Expected 256 black pixels on screen, found %d", n)
+	}
+}
@@ -0,0 +1,88 @@
+package epaper
+
+import (
+	"bytes"
+	"context"
+	"image/color"
+	"time"
+)
+
+// cmdFlashMode is the code of the FLASH MODE command of the IL0371 controller.
+const cmdFlashMode byte = 0xe5
+
+// epd7in5 drives the 7.5 inches black-and-white panel (IL0371 controller), which takes 4 bits per pixel.
+type epd7in5 struct {
+	bus   Bus
+	model Model
+}
+
+// newEpd7in5 creates the driver for the 7.5 inches black-and-white panel.
+func newEpd7in5(b Bus, m Model) Driver {
+	return &epd7in5{bus: b, model: m}
+}
+
+func (d *epd7in5) Capabilities() Capabilities {
+	return Capabilities{
+		Palette: color.Palette{color.Black, color.White},
+		Format:  Format4bpp,
+		Values:  []byte{0x0, 0x3},
+	}
+}
+
+func (d *epd7in5) Init(ctx context.Context) error {
+	if err := d.bus.Reset(); err != nil {
+		return err
+	}
+
+	err := sendSequence(d.bus, []command{
+		{CmdPowerSetting, []byte{0x37, 0x00}},
+		{CmdPanelSetting, []byte{0xcf, 0x08}},
+		{CmdBoosterSoftStart, []byte{0xc7, 0xcc, 0x28}},
+		{CmdPowerOn, nil},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := d.bus.WaitUntilIdle(ctx); err != nil {
+		return err
+	}
+
+	w, h := d.model.Width, d.model.Height
+	return sendSequence(d.bus, []command{
+		{CmdPllControl, []byte{0x3c}},
+		{CmdTemperatureCalibration, []byte{0x00}},
+		{CmdVcomDataIntervalSet, []byte{0x77}},
+		{CmdTconSetting, []byte{0x22}},
+		{CmdTconResolution, []byte{byte(w >> 8), byte(w), byte(h >> 8), byte(h)}},
+		{CmdVcmDcSetting, []byte{0x1e}},
+		{cmdFlashMode, []byte{0x03}},
+	})
+}
+
+func (d *epd7in5) Clear(ctx context.Context) error {
+	// Each byte contains 2 white pixels.
+	return d.bus.Send(CmdDataStartTransimission1, bytes.Repeat([]byte{0x33}, Format4bpp.stride(d.model.Width)*d.model.Height))
+}
+
+func (d *epd7in5) WriteFrame(ctx context.Context, frame []byte) error {
+	return d.bus.Send(CmdDataStartTransimission1, frame)
+}
+
+func (d *epd7in5) Refresh(ctx context.Context) error {
+	if err := d.bus.Send(CmdDisplayRefresh, nil); err != nil {
+		return err
+	}
+	time.Sleep(100 * time.Millisecond)
+	return d.bus.WaitUntilIdle(ctx)
+}
+
+func (d *epd7in5) Sleep(ctx context.Context) error {
+	if err := d.bus.Send(CmdPowerOff, nil); err != nil {
+		return err
+	}
+	if err := d.bus.WaitUntilIdle(ctx); err != nil {
+		return err
+	}
+	return d.bus.Send(CMdDeepSleep, []byte{0xA5})
+}
@@ -0,0 +1,108 @@
+package epaper_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/mcules/go-epaper-lib"
+	"periph.io/x/periph/conn/gpio"
+)
+
+func TestEpd7in5(t *testing.T) {
+	e, debug := newDriverTestEPaper(t, epaper.Model7in5, gpio.High)
+	expectedInit := []byte{
+		0x01, 0x37, 0x00,
+		0x00, 0xcf, 0x08,
+		0x06, 0xc7, 0xcc, 0x28,
+		0x04,
+		0x30, 0x3c,
+		0x41, 0x00,
+		0x50, 0x77,
+		0x60, 0x22,
+		0x61, 0x02, 0x80, 0x01, 0x80,
+		0x82, 0x1e,
+		0xe5, 0x03,
+	}
+	errorMsg := validateByteSlice(debug.Bytes(), expectedInit, "Init function")
+	if len(errorMsg) > 0 {
+		t.Fatal(errorMsg)
+	}
+
+	if err := e.ClearScreen(); err != nil {
+		t.Fatal(err)
+	}
+	debug.Reset()
+
+	// 4 bits per pixel: 0x0 for black and 0x3 for white.
+	draw.Draw(e.Display, image.Rect(0, 0, 3, 1), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+	out := debug.Bytes()
+	if len(out) != 1+640*384/2+1 || out[0] != 0x10 || out[len(out)-1] != 0x12 {
+		t.Fatalf("Expected a frame of %d bytes and a refresh, found %d bytes", 640*384/2, len(out)-2)
+	}
+	errorMsg = validateByteSlice(out[1:4], []byte{0x00, 0x03, 0x33}, "PrintDisplay function")
+	if len(errorMsg) > 0 {
+		t.Fatal(errorMsg)
+	}
+	if n := countBlack(e.Snapshot(), image.Rect(0, 0, 640, 384)); n != 3 {
+		t.Fatalf("Expected 3 black pixels on screen, found %d", n)
+	}
+
+	debug.Reset()
+	if err := e.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	errorMsg = validateByteSlice(debug.Bytes(), []byte{0x02, 0x07, 0xa5}, "Sleep function")
+	if len(errorMsg) > 0 {
+		t.Fatal(errorMsg)
+	}
+}
+
+func TestEpd7in5V2(t *testing.T) {
+	e, debug := newDriverTestEPaper(t, epaper.Model7in5V2, gpio.High)
+	expectedInit := []byte{
+		0x01, 0x07, 0x07, 0x3f, 0x3f,
+		0x04,
+		0x00, 0x1f,
+		0x61, 0x03, 0x20, 0x01, 0xe0,
+		0x15, 0x00,
+		0x50, 0x10, 0x07,
+		0x60, 0x22,
+	}
+	errorMsg := validateByteSlice(debug.Bytes(), expectedInit, "Init function")
+	if len(errorMsg) > 0 {
+		t.Fatal(errorMsg)
+	}
+
+	if err := e.ClearScreen(); err != nil {
+		t.Fatal(err)
+	}
+	if n := countBlack(e.Snapshot(), image.Rect(0, 0, 800, 480)); n != 0 {
+		t.Fatalf("Expected a white screen, found %d black pixels", n)
+	}
+	debug.Reset()
+
+	// The frame is sent inverted: a bit is set for a black pixel.
+	draw.Draw(e.Display, image.Rect(0, 0, 4, 1), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+	out := debug.Bytes()
+	if len(out) != 1+800*480/8+1 || out[0] != 0x13 || out[1] != 0xf0 || out[2] != 0x00 {
+		t.Fatalf("Expected an inverted frame, found %d bytes starting with %v", len(out), out[:3])
+	}
+	if n := countBlack(e.Snapshot(), image.Rect(0, 0, 800, 480)); n != 4 {
+		t.Fatalf("Expected 4 black pixels on screen, found %d", n)
+	}
+
+	if err := e.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	if !e.Emulator().Sleeping() {
+		t.Fatal("Expected the panel to be sleeping")
+	}
+}
@@ -1,20 +1,21 @@
 package epaper
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"image"
 	"image/color"
 	"image/draw"
 	"io"
+	"sync"
 	"time"
 
+	"github.com/anthonynsimon/bild/clone"
 	"github.com/anthonynsimon/bild/paint"
 	"periph.io/x/periph/conn"
 	"periph.io/x/periph/conn/gpio"
 	"periph.io/x/periph/conn/gpio/gpioreg"
 	"periph.io/x/periph/conn/gpio/gpiotest"
-	"periph.io/x/periph/conn/physic"
 	"periph.io/x/periph/conn/spi"
 	"periph.io/x/periph/conn/spi/spireg"
 	"periph.io/x/periph/conn/spi/spitest"
@@ -26,30 +27,141 @@ type Model struct {
 	Width int
 	Height int
 	StartTransmission byte
-	// TODO Color? The working model (2.7in bw) does not work with color...
+	Color color.Color 					// Third ink of the tri-color panels (red or yellow), nil for the black-and-white ones
+
+	// NewDriver creates the Driver for the controller of the panel.
+	// Models without it are driven as the 2.7 inches black-and-white panel.
+	NewDriver func(b Bus, m Model) Driver
 }
 
 // EPaper represents the e-papaer device.
+// Its methods can be called from several goroutines: they are serialized, so commands are never interleaved on the bus.
 type EPaper struct {
+	mu sync.Mutex 						// Serializes the operations
+	displayMu sync.Mutex 				// Serializes the accesses to Display (locked after mu)
+	asyncPrint chan struct{} 			// Closed when the last print of PrintDisplayAsync is done (guarded by displayMu)
+	refreshes []refresh 				// Refreshes to notify to Policy.OnRefresh once mu is released
 	connection conn.Conn
+	port spi.PortCloser 				// SPI port opened by EPaper (nil if supplied by the caller)
+	sleepOnClose bool 					// Close() puts the display in deep sleep
+	closed bool 						// Close() was called
+	maxTxSize int 						// Largest data transfer accepted by connection
+	emulator *Emulator 					// Decodes the commands sent in simulation mode
+	snapshotDir string 					// Directory where the snapshots are saved after each refresh (see RecordSnapshots)
+	snapshots int 						// Number of snapshots saved
 	DataCommandSelection gpio.PinOut 	// High: Data, Low: Command
 	ChipSelection gpio.PinOut 			// Low: active
 	rst gpio.PinOut 					// Low: active
-	Busy gpio.PinIO 					// Low: active
+	Busy gpio.PinIO 					// Active level depends on the controller (see Controller)
+	busyEdges bool 						// Busy detects edges (otherwise it is polled)
+	busyLevel gpio.Level 				// Level of Busy while the controller is busy
+	halfDuplex bool 					// The data line of the panel can be read (SPI mode with spi.HalfDuplex)
 	model Model 						// Details of the model of the display you are using
 	lineWidth int 						// Number of pixels divided by 8 (lines are grouped as a bit in a byte)
-	Display draw.Image 					// This is the image that will be printed to screen
+	rotation Rotation 					// Orientation of Display on the panel
+	logger Logger 						// Traces the operations (can be nil)
+	Display draw.Image 					// This is the image that will be printed to screen (see Draw() to share it between goroutines)
+	driver Driver 						// Controller specific command sequences
+	initialized bool 					// Init() was successful and the display is not sleeping
+
+	// PartialThreshold enables the tracking of changes between frames when greater than zero. PrintDisplay then sends
+	// nothing if the frame did not change, and uses a partial refresh when the changed region covers less than
+	// this fraction of the screen.
+	PartialThreshold float64
+	lastFrame []byte 					// Last frame shown on screen (nil when unknown)
+	mode RefreshMode 					// Mode of the next refreshes (see SetRefreshMode)
+
+	// Classifier maps the colors of Display to the inks of the panel (nil: the closest color, see ColorClassifier).
+	Classifier ColorClassifier
+
+	// Dither diffuses the quantization error of the colors of Display on the panels taking 4 bits per pixel (see
+	// PixelFormat), which gives smoother gradients and photos on the 7-color ones.
+	Dither bool
+
+	// TemperatureSource is the sensor read by the controller (see Temperature).
+	TemperatureSource TemperatureSource
+
+	// ReadTemperature reads the temperature around the panel, in degrees Celsius, from a sensor not connected to the
+	// controller (nil: the controller reads the TemperatureSource).
+	ReadTemperature func() (float64, error)
+	bands []TemperatureBand 			// Waveforms by temperature (see SetTemperatureBands)
+	band int 							// Index of the band whose waveform is loaded (-1 if none)
+
+	// Policy decides when partial refreshes must give way to a full refresh, to limit ghosting.
+	Policy RefreshPolicy
+	partialRefreshes int 				// Partial refreshes since the last full refresh
+	lastFullRefresh time.Time 			// Time of the last full refresh
+
+	// BusyTimeout is the longest time to wait for the display to finish an operation (DefaultBusyTimeout if zero).
+	BusyTimeout time.Duration
 }
 
 var (
+	// Colors of the inks of the tri-color panels.
+	inkRed = color.RGBA{R: 0xff, A: 0xff}
+	inkYellow = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
+
 	// Model2in7bw represents the black-and-white EPD 2.7 inches display
-	Model2in7bw = Model{Width: 176, Height: 264, StartTransmission: 0x13}
+	Model2in7bw = Model{Width: 176, Height: 264, StartTransmission: 0x13, NewDriver: newEpd2in7}
+
+	// Model2in7bwV2 represents the V2 revision of the black-and-white EPD 2.7 inches display (SSD1680 controller)
+	Model2in7bwV2 = Model{Width: 176, Height: 264, NewDriver: newSsd1680}
+
+	// Model1in54V2 represents the V2 revision of the black-and-white EPD 1.54 inches display (SSD1681 controller)
+	Model1in54V2 = Model{Width: 200, Height: 200, NewDriver: newSsd1680}
+
+	// Model2in13V3 represents the V3 revision of the black-and-white EPD 2.13 inches display (SSD1680 controller).
+	// It uses the waveforms of the controller, untested on hardware (the Waveshare examples load their own LUTs).
+	Model2in13V3 = Model{Width: 122, Height: 250, NewDriver: newSsd1680}
+
+	// Model2in13V4 represents the V4 revision of the black-and-white EPD 2.13 inches display (SSD1680 controller)
+	Model2in13V4 = Model{Width: 122, Height: 250, NewDriver: newSsd1680}
+
+	// Model2in9V2 represents the V2 revision of the black-and-white EPD 2.9 inches display (SSD1680 controller).
+	// It uses the waveforms of the controller, untested on hardware (the Waveshare examples load their own LUTs).
+	Model2in9V2 = Model{Width: 128, Height: 296, NewDriver: newSsd1680}
+
+	// Model2in7bV2 represents the V2 revision of the black-white-red EPD 2.7 inches display (SSD1680 controller)
+	Model2in7bV2 = Model{Width: 176, Height: 264, Color: inkRed, NewDriver: newSsd1680}
+
+	// Model4in2b represents the black-white-red EPD 4.2 inches display (UC8176 controller)
+	Model4in2b = Model{Width: 400, Height: 300, Color: inkRed, NewDriver: newEpd4in2b}
+
+	// Model4in2c represents the black-white-yellow EPD 4.2 inches display (UC8176 controller)
+	Model4in2c = Model{Width: 400, Height: 300, Color: inkYellow, NewDriver: newEpd4in2b}
+
+	// Model7in5bV2 represents the V2 revision of the black-white-red EPD 7.5 inches display (UC8179 controller)
+	Model7in5bV2 = Model{Width: 800, Height: 480, Color: inkRed, NewDriver: newEpd7in5bV2}
+
+	// Model7in5 represents the EPD 7.5 inches display (IL0371 controller)
+	Model7in5 = Model{Width: 640, Height: 384, NewDriver: newEpd7in5}
 
-	// Model7in5 represents the EPD 7.5 inches display
-	Model7in5 = Model{Width: 384, Height: 640}
+	// Model7in5V2 represents the V2 revision of the EPD 7.5 inches display (UC8179 controller)
+	Model7in5V2 = Model{Width: 800, Height: 480, NewDriver: newUc8179}
+
+	// Model4in2 represents the black-and-white EPD 4.2 inches display (UC8176 controller)
+	Model4in2 = Model{Width: 400, Height: 300, NewDriver: newEpd4in2}
+
+	// Model5in83V2 represents the V2 revision of the black-and-white EPD 5.83 inches display (UC8179 controller)
+	Model5in83V2 = Model{Width: 648, Height: 480, NewDriver: newUc8179}
+
+	// Model5in65f represents the 7-color EPD 5.65 inches display (ACeP)
+	Model5in65f = Model{Width: 600, Height: 448, NewDriver: newEpd5in65f}
+
+	// Model7in3f represents the 7-color EPD 7.3 inches display (ACeP)
+	Model7in3f = Model{Width: 800, Height: 480, NewDriver: newEpd7in3f}
 )
 
 const (
+	// DefaultBusyTimeout is the longest time to wait for the display to finish an operation.
+	DefaultBusyTimeout = 60 * time.Second
+
+	// defaultMaxTxSize is the largest transfer used when the connection does not report its limit (spidev's default).
+	defaultMaxTxSize = 4096
+
+	// busyPollInterval is the longest time to wait for an edge on BUSY before checking it again.
+	busyPollInterval = 100 * time.Millisecond
+
 	// ResetPin is the default pin where RST pin is connected.
 	ResetPin string = "17"
 
@@ -70,6 +182,12 @@ const (
 	// CmdDataStartTransimission1 TODO ?
 	CmdDataStartTransimission1 byte = 0x10
 
+	// CmdDataStartTransimission2 is used to send the new frame to the display.
+	CmdDataStartTransimission2 byte = 0x13
+
+	// CmdPartialDataStartTransimission2 is used to send the new data of a region (PDTM2).
+	CmdPartialDataStartTransimission2 byte = 0x15
+
 	// CMdDeepSleep puts the screen on a low-power consumption. This should be done when the screen is not expected to be updated for a long time.
 	CMdDeepSleep byte = 0x07
 
@@ -134,9 +252,15 @@ const (
 	// CmdTconSetting is the code for TCON command.
 	CmdTconSetting byte = 0x60
 
+	// CmdTemperatureSensor is the code for TSC command (reads the temperature sensor selected by TSE).
+	CmdTemperatureSensor byte = 0x40
+
 	// CmdTemperatureCalibration is the code for TSE command.
 	CmdTemperatureCalibration byte = 0x41
 
+	// CmdTemperatureRead is the code for TSR command (reads the external temperature sensor).
+	CmdTemperatureRead byte = 0x43
+
 	// CmdVcmDcSetting is the code for VDCS command.
 	CmdVcmDcSetting byte = 0x82
 
@@ -184,7 +308,11 @@ const (
 	VCM_DC_SETTING                 byte = 0x82
 */
 
-func registerPin(name string, simulation bool) gpio.PinIO {
+// openPin returns pin if it was supplied, otherwise the pin called name (or a fake one in simulation mode).
+func openPin(pin gpio.PinIO, name string, simulation bool) gpio.PinIO {
+	if pin != nil {
+		return pin
+	}
 	if simulation {
 		return &gpiotest.Pin{
 			N: name,
@@ -197,17 +325,39 @@ func registerPin(name string, simulation bool) gpio.PinIO {
 
 // New creates a new instance of EPaper with default parameters.
 func New(model Model) (*EPaper, error) {
-	return NewCustom(DataCommandPin, ChipSelectionPin, ResetPin, BusyPin, model, false, nil)
+	return Open(model)
 }
 
 // NewCustom creates a new instance of EPaper with custom parameters. If you have the HAT module, you can use the New() function.
+// See Open() for more settings.
 func NewCustom(dcPin, csPin, rstPin, busyPin string, model Model, simulation bool, debug io.Writer) (*EPaper, error) {
-	if _, err := host.Init(); err != nil {
+	opts := []Option{WithPins(dcPin, csPin, rstPin, busyPin)}
+	if simulation {
+		opts = append(opts, WithSimulation(debug))
+	}
+	return Open(model, opts...)
+}
+
+// NewWithConfig creates a new instance of EPaper with the hardware settings in config (see DefaultConfig()).
+func NewWithConfig(model Model, config Config) (*EPaper, error) {
+	if err := config.validate(); err != nil {
 		return nil, err
 	}
+	if config.SPIFrequency == 0 {
+		config.SPIFrequency = DefaultSPIFrequency
+	}
+	simulation := config.Simulation
+
+	// The registries of pins and ports are only needed for what is neither simulated nor supplied.
+	supplied := config.DataCommand != nil && config.ChipSelection != nil && config.Reset != nil && config.Busy != nil && config.Port != nil
+	if !simulation && !supplied {
+		if _, err := host.Init(); err != nil {
+			return nil, err
+		}
+	}
 
 	// DC Pin
-	dc := registerPin(dcPin, simulation)
+	dc := openPin(config.DataCommand, config.DataCommandPin, simulation)
 	if dc == nil {
 		return nil, errors.New("spi: failed to find DC pin")
 	} else if dc == gpio.INVALID {
@@ -217,7 +367,7 @@ func NewCustom(dcPin, csPin, rstPin, busyPin string, model Model, simulation boo
 	}
 
 	// CS Pin
-	cs := registerPin(csPin, simulation)
+	cs := openPin(config.ChipSelection, config.ChipSelectionPin, simulation)
 	if cs == nil {
 		return nil, errors.New("spi: failed to find CS pin")
 	} else if err := cs.Out(gpio.Low); err != nil {
@@ -225,7 +375,7 @@ func NewCustom(dcPin, csPin, rstPin, busyPin string, model Model, simulation boo
 	}
 
 	// RST Pin
-	rst := registerPin(rstPin, simulation)
+	rst := openPin(config.Reset, config.ResetPin, simulation)
 	if rst == nil {
 		return nil, errors.New("spi: failed to find RST pin")
 	} else if err := rst.Out(gpio.Low); err != nil {
@@ -233,29 +383,58 @@ func NewCustom(dcPin, csPin, rstPin, busyPin string, model Model, simulation boo
 	}
 
 	// BUSY Pin
-	busy := registerPin(busyPin, simulation)
+	busy := openPin(config.Busy, config.BusyPin, simulation)
+	busyEdges := true
 	if busy == nil {
 		return nil, errors.New("spi: failed to find BUSY pin")
-	} else if err := busy.In(gpio.PullDown, gpio.RisingEdge); err != nil {
-		return nil, err
+	} else if err := busy.In(gpio.PullDown, gpio.BothEdges); err != nil {
+		// Some pins (e.g. behind an I/O expander) do not detect edges: BUSY is then polled.
+		if err := busy.In(gpio.PullDown, gpio.NoEdge); err != nil {
+			return nil, err
+		}
+		busyEdges = false
 	}
 
 	// SPI
-	var port spi.PortCloser
-	if simulation {
-		port = spitest.NewRecordRaw(debug)
-	} else {
+	var port spi.Port
+	var closer spi.PortCloser // Port opened here, to be closed on failure or by Close()
+	switch {
+	case config.Port != nil:
+		port = config.Port
+	case simulation:
+		closer = spitest.NewRecordRaw(config.Debug)
+		port = closer
+	default:
 		var err error
-		port, err = spireg.Open("")
+		closer, err = spireg.Open(config.SPIPort)
 		if err != nil {
 			return nil, err
 		}
+		port = closer
+	}
+
+	connection, err := port.Connect(config.SPIFrequency, config.SPIMode, 8)
+	if err != nil {
+		if closer != nil {
+			closer.Close()
+		}
+		return nil, err
 	}
 
-	// TODO official python lib limits to 4 MHz
-	connection, err := port.Connect(5 * physic.MegaHertz, spi.Mode0, 8)
+	// In simulation mode, the commands are also decoded to know what the panel would show.
+	var c conn.Conn = connection
+	var emulator *Emulator
+	if simulation {
+		emulator = NewEmulator(connection, dc, model.Width, model.Height)
+		c = emulator
+		rst = emulator.ResetPin(rst)
+	}
+
+	maxTxSize, err := config.maxTxSize(c)
 	if err != nil {
-		port.Close()
+		if closer != nil {
+			closer.Close()
+		}
 		return nil, err
 	}
 
@@ -264,144 +443,591 @@ func NewCustom(dcPin, csPin, rstPin, busyPin string, model Model, simulation boo
 		lineWidth++
 	}
 
+	width, height := model.Width, model.Height
+	if config.Rotation == Rotation90 || config.Rotation == Rotation270 {
+		width, height = height, width
+	}
+
 	e := &EPaper{
-		connection: connection,
+		connection: c,
+		emulator: emulator,
+		port: closer,
+		sleepOnClose: config.SleepOnClose,
+		maxTxSize: maxTxSize,
 		DataCommandSelection: dc,
 		ChipSelection: cs,
 		rst: rst,
 		Busy: busy,
+		busyEdges: busyEdges,
+		halfDuplex: config.SPIMode&spi.HalfDuplex != 0,
 		model: model,
 		lineWidth: lineWidth,
+		rotation: config.Rotation,
+		logger: config.Logger,
+		BusyTimeout: config.BusyTimeout,
 		Display: paint.FloodFill(
-			image.NewRGBA(image.Rect(0, 0, model.Width, model.Height)),
+			image.NewRGBA(image.Rect(0, 0, width, height)),
 			image.Point{0, 0}, color.RGBA64{255, 255, 255, 255}, 255),
 	}
 
+	newDriver := model.NewDriver
+	if newDriver == nil {
+		newDriver = newEpd2in7
+	}
+	e.driver = newDriver(bus{e}, model)
+	caps := e.driver.Capabilities()
+	e.busyLevel = caps.Controller.busyLevel()
+	if emulator != nil {
+		emulator.setCapabilities(caps)
+	}
+
 	return e, nil
 }
 
 // Reset clear the display (it can also awaken the device).
-func (e *EPaper) Reset() {
+func (e *EPaper) Reset() error {
+	e.mu.Lock()
+	defer e.unlock()
+	return e.reset()
+}
+
+func (e *EPaper) reset() error {
+	if e.closed {
+		return ErrClosed
+	}
 	level := gpio.High
 	for i := 0; i < 3; i++ {
-		e.rst.Out(level)
+		if err := e.rst.Out(level); err != nil {
+			return err
+		}
 		time.Sleep(200 * time.Millisecond)
 		level = !level
 	}
+	return nil
+}
+
+func (e *EPaper) sendCommand(c byte) (err error) {
+	if err := e.DataCommandSelection.Out(gpio.Low); err != nil {
+		return err
+	}
+	defer e.releaseChip(&err)
+	if err := e.ChipSelection.Out(gpio.Low); err != nil {
+		return err
+	}
+	return e.connection.Tx([]byte{c}, nil)
 }
 
-func (e *EPaper) sendCommand(c byte) {
-	e.DataCommandSelection.Out(gpio.Low)
-	e.ChipSelection.Out(gpio.Low)
-	e.connection.Tx([]byte{c}, nil)
-	e.ChipSelection.Out(gpio.High)
+// sendData sends data in a single burst, split in chunks no larger than the maximum transfer size of the connection.
+func (e *EPaper) sendData(data []byte) (err error) {
+	if err := e.DataCommandSelection.Out(gpio.High); err != nil {
+		return err
+	}
+	defer e.releaseChip(&err)
+	if err := e.ChipSelection.Out(gpio.Low); err != nil {
+		return err
+	}
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > e.maxTxSize {
+			chunk = chunk[:e.maxTxSize]
+		}
+		if err := e.connection.Tx(chunk, nil); err != nil {
+			return err
+		}
+		data = data[len(chunk):]
+	}
+	return nil
 }
 
-func (e *EPaper) sendData(d byte) {
-	e.DataCommandSelection.Out(gpio.High)
-	e.ChipSelection.Out(gpio.Low)
-	e.connection.Tx([]byte{d}, nil)
-	e.ChipSelection.Out(gpio.High)
+// releaseChip drives CS high at the end of a transaction, even a failed one, so the next command starts a new one.
+// Its error is stored in err, unless the transaction failed before.
+func (e *EPaper) releaseChip(err *error) {
+	if csErr := e.ChipSelection.Out(gpio.High); *err == nil {
+		*err = csErr
+	}
 }
 
-func (e *EPaper) waitUntilIdle() {
-	for e.Busy.Read() == gpio.Low {
-		time.Sleep(100 * time.Millisecond)
+// waitUntilIdle blocks while BUSY reports the controller is busy. It waits for an edge of BUSY, polling it regularly
+// in case an edge is missed, until ctx is done or BusyTimeout expires.
+func (e *EPaper) waitUntilIdle(ctx context.Context) error {
+	timeout := e.BusyTimeout
+	if timeout <= 0 {
+		timeout = DefaultBusyTimeout
+	}
+	start := time.Now()
+	deadline := start.Add(timeout)
+	defer func() {
+		e.logf("epaper: busy for %s", time.Since(start))
+	}()
+
+	for e.Busy.Read() == e.busyLevel {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return ErrBusyTimeout
+		}
+		if wait > busyPollInterval {
+			wait = busyPollInterval
+		}
+		if e.busyEdges {
+			e.Busy.WaitForEdge(wait)
+		} else {
+			time.Sleep(wait)
+		}
 	}
+	return nil
 }
 
-func (e *EPaper) turnOnDisplay() {
-	e.sendCommand(CmdDisplayRefresh)
-	time.Sleep(100 * time.Millisecond)
-	e.waitUntilIdle()
+// logf traces an operation when a Logger is set.
+func (e *EPaper) logf(format string, v ...interface{}) {
+	if e.logger != nil {
+		e.logger.Printf(format, v...)
+	}
 }
 
 // Init initializes the display config.
 // It should be only used when you put the device to sleep and need to re-init the device.
-func (e *EPaper) Init() {
-	e.Reset()
-
-	e.send(CmdPowerSetting, []byte{0x03, 0x00, 0x2b, 0x2b, 0x09})
-	e.send(CmdBoosterSoftStart, []byte{0x07, 0x07, 0x17})
-
-	// Power optimizations (new)
-	e.send(CmdPowerOptimization, []byte{0x60, 0xa5})
-	e.send(CmdPowerOptimization, []byte{0x89, 0xa5})
-	e.send(CmdPowerOptimization, []byte{0x90, 0x00})
-	e.send(CmdPowerOptimization, []byte{0x93, 0x2a})
-	e.send(CmdPowerOptimization, []byte{0xa0, 0xa5})
-	e.send(CmdPowerOptimization, []byte{0xa1, 0x00})
-	e.send(CmdPowerOptimization, []byte{0x73, 0x41})
-
-	e.send(CmdPartialDisplayRefresh, []byte{0x00})
-
-	e.send(CmdPowerOn, nil)
+func (e *EPaper) Init() error {
+	return e.InitContext(context.Background())
+}
 
-	e.waitUntilIdle()
+// InitContext is like Init, but it gives up when ctx is done.
+func (e *EPaper) InitContext(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.unlock()
 
-	e.send(CmdPanelSetting, []byte{0xaf})
+	e.initialized = false
+	e.lastFrame = nil
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := e.driver.Init(ctx); err != nil {
+		return err
+	}
+	e.initialized = true
+	return nil
+}
 
-	e.send(CmdPllControl, []byte{0x3a})     // 3A 100Hz, 29 150Hz, 39 200Hz, 31 171Hz
+// send writes the command cmd followed by its data. Errors are reported as *CommandError.
+func (e *EPaper) send(cmd byte, data []byte) error {
+	if e.closed {
+		return &CommandError{Command: cmd, Err: ErrClosed}
+	}
+	e.logf("epaper: command 0x%02x with %d bytes of data", cmd, len(data))
+	if err := e.sendCommand(cmd); err != nil {
+		return &CommandError{Command: cmd, Err: err}
+	}
+	if len(data) > 0 {
+		if err := e.sendData(data); err != nil {
+			return &CommandError{Command: cmd, Err: err}
+		}
+	}
+	return nil
+}
 
-	e.send(CmdVcmDcSetting, []byte{0x12})
+// read writes the command cmd, then reads n bytes of data. Errors are reported as *CommandError.
+func (e *EPaper) read(cmd byte, n int) ([]byte, error) {
+	if e.closed {
+		return nil, &CommandError{Command: cmd, Err: ErrClosed}
+	}
+	e.logf("epaper: command 0x%02x reading %d bytes of data", cmd, n)
+	if err := e.sendCommand(cmd); err != nil {
+		return nil, &CommandError{Command: cmd, Err: err}
+	}
 
-	e.send(CmdLutForVcom, Model2in7LutVcomDc)
-	e.send(CmdLutBlue, Model2in7LutWw)
-	e.send(CmdLutWhite, Model2in7LutBw)
-	e.send(CmdLutGray1, Model2in7LutWb)
-	e.send(CmdLutGray2, Model2in7LutBb)
+	data := make([]byte, n)
+	if err := e.receiveData(data); err != nil {
+		return nil, &CommandError{Command: cmd, Err: err}
+	}
+	return data, nil
 }
 
-func (e *EPaper) send(cmd byte, data []byte) {
-	e.sendCommand(cmd)
-	if data != nil {
-		for _, d := range data {
-			e.sendData(d)
-		}
+// receiveData fills data with the bytes read from the display.
+func (e *EPaper) receiveData(data []byte) (err error) {
+	if err := e.DataCommandSelection.Out(gpio.High); err != nil {
+		return err
+	}
+	defer e.releaseChip(&err)
+	if err := e.ChipSelection.Out(gpio.Low); err != nil {
+		return err
 	}
+	return e.connection.Tx(nil, data)
 }
 
 // ClearScreen erases anything that is on screen.
-func (e *EPaper) ClearScreen() {
+func (e *EPaper) ClearScreen() error {
+	return e.ClearScreenContext(context.Background())
+}
+
+// ClearScreenContext is like ClearScreen, but it gives up when ctx is done.
+func (e *EPaper) ClearScreenContext(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.unlock()
+
+	if !e.initialized {
+		return ErrNotInitialized
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	//draw.Draw(e.Display, e.Display.Bounds(), paint.FloodFill(e.Display, image.Point{0, 0}, color.RGBA{255, 255, 255, 255}, 255), image.Point{0, 0}, draw.Src)
+	e.displayMu.Lock()
 	e.Display = paint.FloodFill(
 		image.Rect(0, 0, e.Display.Bounds().Dx(), e.Display.Bounds().Dy()),
 		image.Point{0, 0}, color.RGBA{255, 255, 255, 255}, 255)
+	e.displayMu.Unlock()
 
-	data := bytes.Repeat([]byte{0xFF}, e.model.Height * e.model.Width / 8)	// Each byte contains 8 pixels
+	e.lastFrame = nil
+	if err := e.selectWaveform(ctx); err != nil {
+		return err
+	}
+	if err := e.driver.Clear(ctx); err != nil {
+		return err
+	}
+	if err := e.driver.Refresh(ctx); err != nil {
+		return err
+	}
+	return e.fullRefreshDone(e.convert())
+}
 
-	e.send(CmdDataStartTransimission1, data)
-	e.send(0x13, data)
-	e.turnOnDisplay()
+// unlock releases mu, then notifies the refreshes done meanwhile to Policy.OnRefresh, which can call EPaper again.
+func (e *EPaper) unlock() {
+	refreshes, onRefresh := e.refreshes, e.Policy.OnRefresh
+	e.refreshes = nil
+	e.mu.Unlock()
+
+	if onRefresh != nil {
+		for _, r := range refreshes {
+			onRefresh(r.r, r.partial)
+		}
+	}
 }
 
 // PrintDisplay updates the screen with the contents of EPaper.Display.
-func (e *EPaper) PrintDisplay() {
+func (e *EPaper) PrintDisplay() error {
+	return e.PrintDisplayContext(context.Background())
+}
+
+// PrintDisplayContext is like PrintDisplay, but it gives up when ctx is done.
+func (e *EPaper) PrintDisplayContext(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.unlock()
+	return e.printDisplay(ctx)
+}
+
+// PrintDisplayAsync is like PrintDisplay, but it returns at once, even while another operation is in progress. The
+// returned channel receives the result once the panel is refreshed. The contents of EPaper.Display are captured before
+// returning, so the next frame can be drawn meanwhile. The frames are printed in the order of the calls, but the
+// other operations called meanwhile may run first: wait for the result to order them.
+func (e *EPaper) PrintDisplayAsync() <-chan error {
+	return e.PrintDisplayAsyncContext(context.Background())
+}
+
+// PrintDisplayAsyncContext is like PrintDisplayAsync, but the refresh gives up when ctx is done.
+func (e *EPaper) PrintDisplayAsyncContext(ctx context.Context) <-chan error {
+	done := make(chan error, 1)
+	e.displayMu.Lock()
+	display := clone.AsRGBA(e.Display)
+	previous, next := e.asyncPrint, make(chan struct{})
+	e.asyncPrint = next
+	e.displayMu.Unlock()
+
+	go func() {
+		defer close(next)
+		if previous != nil {
+			<-previous
+		}
+		e.mu.Lock()
+		defer e.unlock()
+		done <- e.printFrame(ctx, e.convertImage(display))
+	}()
+	return done
+}
+
+// PrintDisplayMode is like PrintDisplay, but the panel is refreshed with the mode m, this time only (see
+// SetRefreshMode).
+func (e *EPaper) PrintDisplayMode(m RefreshMode) error {
+	return e.PrintDisplayModeContext(context.Background(), m)
+}
+
+// PrintDisplayModeContext is like PrintDisplayMode, but it gives up when ctx is done.
+func (e *EPaper) PrintDisplayModeContext(ctx context.Context, m RefreshMode) error {
+	e.mu.Lock()
+	defer e.unlock()
+
+	previous := e.mode
+	if err := e.setRefreshMode(m); err != nil {
+		return err
+	}
+	err := e.printDisplay(ctx)
+	if restoreErr := e.setRefreshMode(previous); err == nil {
+		err = restoreErr
+	}
+	return err
+}
+
+// SetRefreshMode selects how the next refreshes are done. It returns ErrUnsupported if the panel does not support m
+// (see Capabilities.Modes).
+func (e *EPaper) SetRefreshMode(m RefreshMode) error {
+	e.mu.Lock()
+	defer e.unlock()
+	return e.setRefreshMode(m)
+}
+
+// setRefreshMode is SetRefreshMode, without locking.
+func (e *EPaper) setRefreshMode(m RefreshMode) error {
+	caps := e.driver.Capabilities()
+	if !caps.supports(m) {
+		return ErrUnsupported
+	}
+
+	// The partial refreshes are done by EPaper, the full ones by the driver.
+	full := m
+	if m == ModePartial {
+		full = ModeFull
+	}
+	if d, ok := e.driver.(ModeDriver); ok {
+		if err := d.SetMode(full); err != nil {
+			return err
+		}
+		if after := d.Capabilities(); after.Format != caps.Format {
+			// The frames of the new mode cannot be compared with the last one.
+			e.lastFrame = nil
+			if e.emulator != nil {
+				e.emulator.setCapabilities(after)
+			}
+		}
+	} else if full != ModeFull {
+		return ErrUnsupported
+	}
+	e.mode = m
+	return nil
+}
+
+// SetWaveform uses w for the next full refreshes, instead of the built-in waveform of the panel (restored when w is
+// nil), and removes the temperature bands (see SetTemperatureBands). w is copied. It returns an error wrapping ErrInvalidWaveform if
+// w does not suit the controller of the panel, and ErrUnsupported if its driver cannot use custom waveforms.
+func (e *EPaper) SetWaveform(w *Waveform) error {
+	e.mu.Lock()
+	defer e.unlock()
 
-	imgArray := e.convert()
+	d, ok := e.driver.(WaveformDriver)
+	if !ok {
+		return ErrUnsupported
+	}
+	if w != nil {
+		if err := w.Validate(d.Capabilities().Controller); err != nil {
+			return err
+		}
+		// The changes made to w afterwards have no effect.
+		copied := w.clone()
+		w = &copied
+	}
+	e.bands = nil
+	return d.SetWaveform(w)
+}
 
-	// This command is required before sending data to print on screen. Each model uses its own code.
-	e.sendCommand(e.model.StartTransmission)
+// Draw calls f with EPaper.Display, which is not read meanwhile. It lets a goroutine change the image while others
+// print it.
+func (e *EPaper) Draw(f func(display draw.Image)) {
+	e.displayMu.Lock()
+	defer e.displayMu.Unlock()
+	f(e.Display)
+}
 
+func (e *EPaper) printDisplay(ctx context.Context) error {
 	// Processing each line
 	// Processing the pixel group (each byte represents 8 chars, see README.md for details)
-	//for i, b := range img {
-	for _, b := range imgArray {
-		e.sendData(b)
-		// TODO Debug only.
-		//fmt.Printf("%08b ", b)
-		//if i + 1 % 22 == 0 {
-		//	fmt.Println();
-		//}
+	return e.printFrame(ctx, e.convert())
+}
+
+// printFrame prints frame, which comes from convert().
+func (e *EPaper) printFrame(ctx context.Context, frame []byte) error {
+	if !e.initialized {
+		return ErrNotInitialized
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	forced := e.Policy.fullRefreshDue(e.partialRefreshes, e.lastFullRefresh)
+
+	if (e.PartialThreshold > 0 || e.mode == ModePartial) && e.lastFrame != nil {
+		r := e.diff(e.lastFrame, frame)
+		if r.Empty() {
+			return nil
+		}
+
+		screen := e.lineWidth * 8 * e.model.Height
+		small := float64(r.Dx() * r.Dy()) < e.PartialThreshold * float64(screen)
+		if partial, ok := e.partialDriver(); ok && !forced && (small || e.mode == ModePartial) {
+			return e.refreshRegion(ctx, partial, frame, r)
+		}
 	}
 
-	e.turnOnDisplay()
+	return e.fullRefresh(ctx, frame, forced && e.Policy.ClearFlash)
+}
+
+// fullRefresh prints frame on the whole screen, flashing it white first if flash is true.
+func (e *EPaper) fullRefresh(ctx context.Context, frame []byte, flash bool) error {
+	e.lastFrame = nil
+	if err := e.selectWaveform(ctx); err != nil {
+		return err
+	}
+	if flash {
+		if err := e.driver.Clear(ctx); err != nil {
+			return err
+		}
+		if err := e.driver.Refresh(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := e.driver.WriteFrame(ctx, frame); err != nil {
+		return err
+	}
+	if err := e.driver.Refresh(ctx); err != nil {
+		return err
+	}
+	return e.fullRefreshDone(frame)
+}
+
+// fullRefreshDone keeps track of a successful full refresh showing frame.
+func (e *EPaper) fullRefreshDone(frame []byte) error {
+	e.lastFrame = frame
+	e.partialRefreshes = 0
+	e.lastFullRefresh = e.Policy.now()
+	return e.refreshed(image.Rect(0, 0, e.lineWidth * 8, e.model.Height), false)
+}
+
+// DirtyRegion returns the region of EPaper.Display that changed since it was last printed (widened to multiples of 8
+// pixels on the X axis of the panel). It is empty when there is nothing to print, and covers the whole screen when the content of the
+// screen is unknown.
+func (e *EPaper) DirtyRegion() image.Rectangle {
+	e.mu.Lock()
+	defer e.unlock()
+
+	if e.lastFrame == nil {
+		return e.fromPanel(image.Rect(0, 0, e.lineWidth * 8, e.model.Height))
+	}
+	return e.fromPanel(e.diff(e.lastFrame, e.convert()))
+}
+
+// PrintRegion updates only the region rect of the screen with the contents of EPaper.Display, using a partial refresh.
+// The region is widened so its bounds on the X axis of the panel are multiples of 8 pixels.
+func (e *EPaper) PrintRegion(rect image.Rectangle) error {
+	return e.PrintRegionContext(context.Background(), rect)
+}
+
+// PrintRegionContext is like PrintRegion, but it gives up when ctx is done.
+func (e *EPaper) PrintRegionContext(ctx context.Context, rect image.Rectangle) error {
+	e.mu.Lock()
+	defer e.unlock()
+
+	if !e.initialized {
+		return ErrNotInitialized
+	}
+	partial, ok := e.partialDriver()
+	if !ok {
+		return ErrUnsupported
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r := e.alignRegion(e.toPanel(rect))
+	if r.Empty() {
+		return nil
+	}
+	if e.Policy.fullRefreshDue(e.partialRefreshes, e.lastFullRefresh) {
+		return e.fullRefresh(ctx, e.convert(), e.Policy.ClearFlash)
+	}
+	return e.refreshRegion(ctx, partial, e.convert(), r)
+}
+
+// partialDriver returns the driver if it supports partial refreshes. They are only done with frames of 1 bit per pixel.
+func (e *EPaper) partialDriver() (PartialDriver, bool) {
+	partial, ok := e.driver.(PartialDriver)
+	return partial, ok && e.driver.Capabilities().Format == Format1bpp
+}
+
+// refreshRegion prints the aligned region r of frame with a partial refresh and keeps track of the new screen contents.
+func (e *EPaper) refreshRegion(ctx context.Context, partial PartialDriver, frame []byte, r image.Rectangle) error {
+	last := e.lastFrame
+	e.lastFrame = nil
+	if err := partial.RefreshRegion(ctx, r, e.window(frame, r)); err != nil {
+		return err
+	}
+
+	if last != nil {
+		for j := r.Min.Y; j < r.Max.Y; j++ {
+			start := j * e.lineWidth + r.Min.X / 8
+			end := j * e.lineWidth + r.Max.X / 8
+			copy(last[start:end], frame[start:end])
+		}
+		e.lastFrame = last
+	}
+	e.partialRefreshes++
+	return e.refreshed(r, true)
 }
 
 // Sleep put the display in power-saving mode.
 // You can use Reset() to awaken and Init() to re-initialize the display.
-func (e *EPaper) Sleep() {
-	e.sendCommand(CmdPowerOff)
-	e.waitUntilIdle()
-	e.send(CMdDeepSleep, []byte{0xA5})
+func (e *EPaper) Sleep() error {
+	e.mu.Lock()
+	defer e.unlock()
+	return e.sleep()
+}
+
+func (e *EPaper) sleep() error {
+	e.initialized = false
+	return e.driver.Sleep(context.Background())
+}
+
+// Close releases the display: it is put in deep sleep (unless disabled, see Config.SleepOnClose), its pins are left
+// in a safe state (RST and DC low, CS high, no edge detection on BUSY) and the SPI port is closed (unless it was supplied by the caller).
+// The EPaper can not be used anymore afterwards.
+func (e *EPaper) Close() error {
+	e.mu.Lock()
+	defer e.unlock()
+
+	if e.closed {
+		return nil
+	}
+
+	var err error
+	if e.sleepOnClose && e.initialized {
+		err = e.sleep()
+	}
+	e.initialized = false
+	e.closed = true
+
+	for _, p := range []struct {
+		pin   gpio.PinOut
+		level gpio.Level
+	}{
+		{e.rst, gpio.Low},
+		{e.DataCommandSelection, gpio.Low},
+		{e.ChipSelection, gpio.High},
+	} {
+		if pinErr := p.pin.Out(p.level); err == nil {
+			err = pinErr
+		}
+	}
+	if e.busyEdges {
+		// Stops the edge detection of BUSY.
+		if pinErr := e.Busy.In(gpio.PullNoChange, gpio.NoEdge); err == nil {
+			err = pinErr
+		}
+	}
+
+	if e.port != nil {
+		if portErr := e.port.Close(); err == nil {
+			err = portErr
+		}
+	}
+	return err
 }
@@ -2,11 +2,18 @@ package epaper_test
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
 	"testing"
+	"time"
 
 	"github.com/mcules/go-epaper-lib"
 	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
 )
 
 var (
@@ -33,8 +40,10 @@ var (
 
 	ExpectedClearScreenResult = []byte{
 		0x10, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
-		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x13, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
-		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x12,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0x13, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x12,
 	}
 )
 
@@ -98,3 +107,315 @@ func TestSleep(t *testing.T) {
 	}
 	debug.Reset()
 }
+
+// failingWriter simulates a broken SPI bus.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("bus failure")
+}
+
+// dataFailingWriter simulates a SPI bus failing on the transfers of data, longer than the commands.
+type dataFailingWriter struct{}
+
+func (dataFailingWriter) Write(p []byte) (int, error) {
+	if len(p) > 1 {
+		return 0, errors.New("bus failure")
+	}
+	return len(p), nil
+}
+
+func TestNotInitialized(t *testing.T) {
+	// Create a dummy "epaper"
+	// (to create a real one, use the example source code, this won't work!)
+	debug := new(bytes.Buffer)
+	e, err := epaper.NewCustom("", "", "", "", ModelSim, true, debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.PrintDisplay(); !errors.Is(err, epaper.ErrNotInitialized) {
+		t.Fatalf("Expected ErrNotInitialized from PrintDisplay, found %v", err)
+	}
+
+	if err := e.ClearScreen(); !errors.Is(err, epaper.ErrNotInitialized) {
+		t.Fatalf("Expected ErrNotInitialized from ClearScreen, found %v", err)
+	}
+
+	if debug.Len() != 0 {
+		t.Fatalf("Expected nothing sent to the device, found %v", debug.Bytes())
+	}
+}
+
+func TestCommandError(t *testing.T) {
+	// Create a dummy "epaper" whose SPI bus fails on every transfer.
+	e, err := epaper.NewCustom("", "", "", "", ModelSim, true, failingWriter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = e.Sleep()
+	var cmdErr *epaper.CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("Expected a CommandError, found %v", err)
+	}
+	if cmdErr.Command != epaper.CmdPowerOff {
+		t.Fatalf("Expected failure on command 0x%02x, found 0x%02x", epaper.CmdPowerOff, cmdErr.Command)
+	}
+	if cs := e.ChipSelection.(gpio.PinIn).Read(); cs != gpio.High {
+		t.Fatal("Expected CS to be released after a failed command")
+	}
+
+	// The same after a failed transfer of data.
+	e, err = epaper.NewCustom("", "", "", "", ModelSim, true, dataFailingWriter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Busy.Out(gpio.High)
+	if err := e.Init(); !errors.As(err, &cmdErr) {
+		t.Fatalf("Expected a CommandError, found %v", err)
+	}
+	if cs := e.ChipSelection.(gpio.PinIn).Read(); cs != gpio.High {
+		t.Fatal("Expected CS to be released after a failed transfer of data")
+	}
+}
+
+func TestBusyTimeout(t *testing.T) {
+	// Create a dummy "epaper"
+	// (to create a real one, use the example source code, this won't work!)
+	debug := new(bytes.Buffer)
+	e, err := epaper.NewCustom("", "", "", "", ModelSim, true, debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// BUSY stays low, as if the HAT was disconnected.
+	e.BusyTimeout = 50 * time.Millisecond
+	if err := e.Sleep(); !errors.Is(err, epaper.ErrBusyTimeout) {
+		t.Fatalf("Expected ErrBusyTimeout, found %v", err)
+	}
+}
+
+func TestInitContextCanceled(t *testing.T) {
+	// Create a dummy "epaper"
+	// (to create a real one, use the example source code, this won't work!)
+	debug := new(bytes.Buffer)
+	e, err := epaper.NewCustom("", "", "", "", ModelSim, true, debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 700*time.Millisecond)
+	defer cancel()
+
+	// BUSY stays low, so the context expires while waiting for the power on.
+	if err := e.InitContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, found %v", err)
+	}
+
+	if err := e.PrintDisplayContext(context.Background()); !errors.Is(err, epaper.ErrNotInitialized) {
+		t.Fatalf("Expected ErrNotInitialized after a failed init, found %v", err)
+	}
+}
+
+func TestWaitForBusyEdge(t *testing.T) {
+	// Create a dummy "epaper"
+	// (to create a real one, use the example source code, this won't work!)
+	debug := new(bytes.Buffer)
+	e, err := epaper.NewCustom("", "", "", "", ModelSim, true, debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulating the rising edge of BUSY when the display finishes powering off.
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		e.Busy.(*gpiotest.Pin).EdgesChan <- gpio.High
+	}()
+
+	if err := e.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+
+	errorMsg := validateByteSlice(debug.Bytes(), []byte{0x02, 0x07, 0xa5}, "Sleep function")
+	if len(errorMsg) > 0 {
+		t.Fatal(errorMsg)
+	}
+}
+
+func TestPrintRegion(t *testing.T) {
+	var expectedRegionResult []byte
+	for _, lut := range []struct {
+		cmd  byte
+		data []byte
+	}{
+		{0x20, epaper.Model2in7PartialLutVcomDc},
+		{0x21, epaper.Model2in7PartialLutWw},
+		{0x22, epaper.Model2in7PartialLutBw},
+		{0x23, epaper.Model2in7PartialLutWb},
+		{0x24, epaper.Model2in7PartialLutBb},
+	} {
+		expectedRegionResult = append(expectedRegionResult, lut.cmd)
+		expectedRegionResult = append(expectedRegionResult, lut.data...)
+	}
+	expectedRegionResult = append(expectedRegionResult,
+		0x15, 0x00, 0x00, 0x00, 0x03, 0x00, 0x08, 0x00, 0x03, 0xc7, 0xc7, 0xc7,
+		0x16, 0x00, 0x00, 0x00, 0x03, 0x00, 0x08, 0x00, 0x03,
+	)
+
+	// Create a dummy "epaper"
+	// (to create a real one, use the example source code, this won't work!)
+	debug := new(bytes.Buffer)
+	e, err := epaper.NewCustom("", "", "", "", ModelSim, true, debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Forcing the BUSY to High to avoid being blocked because of WaitUntilIdle().
+	// Do not do this on real cases!
+	e.Busy.Out(gpio.High)
+
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.ClearScreen(); err != nil {
+		t.Fatal(err)
+	}
+	debug.Reset()
+
+	// Draw a small black rectangle and print only the region around it.
+	region := image.Rect(2, 3, 5, 6)
+	draw.Draw(e.Display, region, image.NewUniform(color.Black), image.Point{}, draw.Src)
+	if err := e.PrintRegion(region); err != nil {
+		t.Fatal(err)
+	}
+
+	errorMsg := validateByteSlice(debug.Bytes(), expectedRegionResult, "PrintRegion function")
+	if len(errorMsg) > 0 {
+		t.Fatal(errorMsg)
+	}
+	debug.Reset()
+
+	// A region outside of the screen is ignored.
+	if err := e.PrintRegion(image.Rect(20, 30, 40, 50)); err != nil {
+		t.Fatal(err)
+	}
+	if debug.Len() != 0 {
+		t.Fatalf("Expected nothing sent to the device, found %v", debug.Bytes())
+	}
+
+	// The next full refresh restores the full LUTs after uploading the frame (0x13 followed by 2x20 bytes).
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+	expectedLut := append([]byte{0x20}, epaper.Model2in7LutVcomDc...)
+	errorMsg = validateByteSlice(debug.Bytes()[41:41+len(expectedLut)], expectedLut, "PrintDisplay function")
+	if len(errorMsg) > 0 {
+		t.Fatal(errorMsg)
+	}
+}
+
+func TestDirtyTracking(t *testing.T) {
+	// Create a dummy "epaper"
+	// (to create a real one, use the example source code, this won't work!)
+	debug := new(bytes.Buffer)
+	e, err := epaper.NewCustom("", "", "", "", ModelSim, true, debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.PartialThreshold = 0.25
+
+	// Forcing the BUSY to High to avoid being blocked because of WaitUntilIdle().
+	// Do not do this on real cases!
+	e.Busy.Out(gpio.High)
+
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.DirtyRegion(); got != image.Rect(0, 0, 10, 20) {
+		t.Fatalf("Expected the whole screen to be dirty after Init, found %v", got)
+	}
+	if err := e.ClearScreen(); err != nil {
+		t.Fatal(err)
+	}
+	debug.Reset()
+
+	// Nothing changed: nothing is sent.
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+	if debug.Len() != 0 {
+		t.Fatalf("Expected nothing sent to the device, found %v", debug.Bytes())
+	}
+
+	// A small change uses a partial refresh.
+	draw.Draw(e.Display, image.Rect(2, 3, 5, 6), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	if got := e.DirtyRegion(); got != image.Rect(0, 3, 8, 6) {
+		t.Fatalf("Expected dirty region (0,3)-(8,6), found %v", got)
+	}
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(debug.Bytes(), []byte{0x16, 0x00, 0x00, 0x00, 0x03, 0x00, 0x08, 0x00, 0x03}) {
+		t.Fatalf("Expected a partial refresh, found %v", debug.Bytes())
+	}
+	if !e.DirtyRegion().Empty() {
+		t.Fatalf("Expected no dirty region after printing, found %v", e.DirtyRegion())
+	}
+	debug.Reset()
+
+	// A large change uses a full refresh.
+	draw.Draw(e.Display, image.Rect(0, 0, 10, 10), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+	if debug.Bytes()[0] != 0x13 || debug.Bytes()[debug.Len()-1] != 0x12 {
+		t.Fatalf("Expected a full refresh, found %v", debug.Bytes())
+	}
+}
+
+// countingWriter counts the SPI transfers made on a simulated bus.
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestBurstWrites(t *testing.T) {
+	// Create a dummy "epaper"
+	// (to create a real one, use the example source code, this won't work!)
+	debug := new(countingWriter)
+	e, err := epaper.NewCustom("", "", "", "", ModelSim, true, debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Forcing the BUSY to High to avoid being blocked because of WaitUntilIdle().
+	// Do not do this on real cases!
+	e.Busy.Out(gpio.High)
+
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.ClearScreen(); err != nil {
+		t.Fatal(err)
+	}
+	debug.Reset()
+	debug.writes = 0
+
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+
+	// One transfer for the command 0x13, one for the whole frame and one for the command 0x12.
+	if debug.writes != 3 {
+		t.Fatalf("Expected 3 transfers, found %d", debug.writes)
+	}
+	if debug.Len() != 42 {
+		t.Fatalf("Expected 42 bytes sent, found %d", debug.Len())
+	}
+}
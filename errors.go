@@ -0,0 +1,48 @@
+package epaper
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrBusyTimeout is returned when the controller stays busy for longer than expected.
+	ErrBusyTimeout = errors.New("epaper: timeout waiting for the display to be idle")
+
+	// ErrUnsupported is returned when the driver of the display does not support an operation.
+	ErrUnsupported = errors.New("epaper: operation not supported by the display")
+
+	// ErrInvalidConfig is returned when the hardware settings are not valid.
+	ErrInvalidConfig = errors.New("epaper: invalid configuration")
+
+	// ErrClosed is returned when the display is used after Close().
+	ErrClosed = errors.New("epaper: display is closed")
+
+	// ErrSuperseded is reported for a frame replaced by a newer one before it was printed (see Worker).
+	ErrSuperseded = errors.New("epaper: frame superseded by a newer one")
+
+	// ErrInvalidWaveform is returned when a Waveform does not suit the controller of the display, or cannot be parsed.
+	ErrInvalidWaveform = errors.New("epaper: invalid waveform")
+
+	// ErrInvalidTemperature is returned when the temperature read from the controller makes no sense (e.g. the data
+	// line of the panel cannot be read).
+	ErrInvalidTemperature = errors.New("epaper: invalid temperature reading")
+
+	// ErrNotInitialized is returned when the display is used before Init() (or after Sleep()).
+	ErrNotInitialized = errors.New("epaper: display is not initialized")
+)
+
+// CommandError reports a failure while sending a command (or its data) to the controller.
+type CommandError struct {
+	Command byte  // Command that failed
+	Err     error // Underlying error (SPI or GPIO)
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("epaper: command 0x%02x: %v", e.Command, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,89 @@
+package epaper_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/mcules/go-epaper-lib"
+	"periph.io/x/periph/conn/gpio"
+)
+
+func TestGray4(t *testing.T) {
+	e, debug := newDriverTestEPaper(t, epaper.Model2in7bw, gpio.High)
+	if err := e.SetRefreshMode(epaper.ModeGray4); err != nil {
+		t.Fatal(err)
+	}
+
+	// A stripe of each level, with slightly different grays.
+	levels := []uint8{0x00, 0x55, 0xaa, 0xff}
+	for i, y := range []uint8{0x10, 0x60, 0xa0, 0xf0} {
+		draw.Draw(e.Display, image.Rect(i*8, 0, i*8+8, 10), image.NewUniform(color.Gray{Y: y}), image.Point{}, draw.Src)
+	}
+	debug.Reset()
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both planes are sent, then the grayscale settings and LUTs are loaded.
+	out := debug.Bytes()
+	if !bytes.HasPrefix(out, []byte{0x10, 0x00, 0x00, 0xff, 0xff}) {
+		t.Fatalf("Expected the high bits of the levels in DTM1, found %v", out[:5])
+	}
+	if !bytes.Contains(out, []byte{0x13, 0x00, 0xff, 0x00, 0xff}) {
+		t.Fatal("Expected the low bits of the levels in DTM2")
+	}
+	if !bytes.Contains(out, []byte{0x00, 0xbf, 0x30, 0x90, 0x61, 0x00, 0xb0, 0x01, 0x08, 0x50, 0x97}) ||
+		!bytes.Contains(out, []byte{0x25, 0x40, 0x0a}) {
+		t.Fatal("Expected the grayscale settings and LUTs to be loaded")
+	}
+
+	img := e.Emulator().Image()
+	for i, y := range levels {
+		if n := countColor(img, image.Rect(i*8, 0, i*8+8, 10), color.Gray{Y: y}); n != 80 {
+			t.Fatalf("Expected stripe %d to be printed with level 0x%02x, found %d pixels", i, y, n)
+		}
+	}
+
+	// Back to black and white: the settings are restored.
+	if err := e.SetRefreshMode(epaper.ModeFull); err != nil {
+		t.Fatal(err)
+	}
+	debug.Reset()
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(debug.Bytes(), []byte{0x00, 0xaf, 0x30, 0x3a, 0x61, 0x00, 0xb0, 0x01, 0x08, 0x50, 0xd7}) {
+		t.Fatal("Expected the black-and-white settings to be restored")
+	}
+	img = e.Emulator().Image()
+	if n := countBlack(img, image.Rect(0, 0, 16, 10)); n != 160 {
+		t.Fatalf("Expected the 2 darkest stripes to be black, found %d black pixels", n)
+	}
+}
+
+func TestPrintDisplayMode(t *testing.T) {
+	e, debug := newDriverTestEPaper(t, epaper.Model2in7bw, gpio.High)
+	if err := e.PrintDisplayMode(epaper.ModeFast); err != epaper.ErrUnsupported {
+		t.Fatalf("Expected ErrUnsupported, found %v", err)
+	}
+
+	draw.Draw(e.Display, image.Rect(0, 0, 8, 10), image.NewUniform(color.Gray{Y: 0x60}), image.Point{}, draw.Src)
+	if err := e.PrintDisplayMode(epaper.ModeGray4); err != nil {
+		t.Fatal(err)
+	}
+	if n := countColor(e.Emulator().Image(), image.Rect(0, 0, 8, 10), color.Gray{Y: 0x55}); n != 80 {
+		t.Fatalf("Expected a dark gray square, found %d pixels", n)
+	}
+
+	// The next refresh is in black and white again.
+	debug.Reset()
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(debug.Bytes(), []byte{0x00, 0xaf}) {
+		t.Fatal("Expected the black-and-white settings to be restored")
+	}
+}
@@ -12,6 +12,8 @@ import (
 
 // AddLayer puts img on top of the previous layers prepared to be printed. Function Clearscreen() will also delete any prepared layer.
 func (e *EPaper) AddLayer(img image.Image, startX, startY int, transparent bool) {
+	e.displayMu.Lock()
+	defer e.displayMu.Unlock()
 
 	selectionRectangle := image.Rect(startX, startY, startX + img.Bounds().Dx(), startY + img.Bounds().Dy())
 
@@ -33,17 +35,45 @@ func (e *EPaper) AddLayer(img image.Image, startX, startY int, transparent bool)
 
 // Convert the input image into a ready-to-display byte buffer.
 func (e *EPaper) convert() []byte {
+	e.displayMu.Lock()
+	defer e.displayMu.Unlock()
+	return e.convertImage(e.Display)
+}
+
+// convertImage converts display, an image like EPaper.Display, into a ready-to-display byte buffer.
+func (e *EPaper) convertImage(display image.Image) []byte {
+	caps := e.driver.Capabilities()
+	switch caps.Format {
+	case Format4bpp:
+		return e.convert4bpp(display, caps)
+	case FormatPlanes:
+		// The black plane, then the plane of the third color.
+		black := e.convertPlane(display, caps.Palette, func(index int) bool { return index != 0 })
+		return append(black, e.convertPlane(display, caps.Palette, func(index int) bool { return index != 2 })...)
+	case FormatBitPlanes:
+		high := e.convertPlane(display, caps.Palette, func(index int) bool { return index & 2 != 0 })
+		return append(high, e.convertPlane(display, caps.Palette, func(index int) bool { return index & 1 != 0 })...)
+	}
+	return e.convertPlane(display, caps.Palette, func(index int) bool { return index != 0 })
+}
+
+// convertPlane converts display into a buffer of 1 bit per pixel. The bit is set for the pixels whose index in palette
+// satisfies set.
+func (e *EPaper) convertPlane(display image.Image, palette color.Palette, set func(index int) bool) []byte {
 	var clearBackground byte = 0x00
 
 	// Processing each line from the original image. If image is too large, we'll cap to the screen size.
-	height := e.Display.Bounds().Dy()
+	height := display.Bounds().Dy()
 	// if e.display.Bounds().Dy() > e.model.Height {
 	// 	height = e.model.Height
 	// }
-	width := e.Display.Bounds().Dx()
+	width := display.Bounds().Dx()
 	// if e.display.Bounds().Dx() > e.model.Width {
 	// 	width = e.model.Width
 	// }
+	if e.rotation == Rotation90 || e.rotation == Rotation270 {
+		width, height = height, width
+	}
 
 	// Create the output array (each element represents 8 pixels, so we need a smaller array than the original matrix.)
 	buffer := bytes.Repeat([]byte{0xFF}, e.lineWidth * e.model.Height)
@@ -55,7 +85,7 @@ func (e *EPaper) convert() []byte {
 			newValue = newValue << 1
 
 			// If color in pixel (x,y) is black, we mark it on the correct bit in the new element for the array.
-			if color.Palette([]color.Color{color.Black, color.White}).Index(e.Display.At(i, j)) == 1 {
+			if set(e.classify(display.At(e.logical(i, j)), palette)) {
 				newValue |= 0x01
 			}
 
@@ -65,12 +95,170 @@ func (e *EPaper) convert() []byte {
 				newValue = clearBackground
 			}
 		}
+
+		// The last byte of a line is incomplete when the width is not a multiple of 8: it is padded with white pixels.
+		if pad := uint(8 - width % 8); pad < 8 {
+			buffer[(width / 8) + (j * width) + offset] = newValue << pad | byte(1 << pad - 1)
+			newValue = clearBackground
+		}
 		offset += e.lineWidth - width
 	}
 
 	return buffer
 }
 
+// convert4bpp converts display into a buffer with 2 pixels per byte (see Format4bpp).
+func (e *EPaper) convert4bpp(display image.Image, caps Capabilities) []byte {
+	stride := Format4bpp.stride(e.model.Width)
+	buffer := make([]byte, stride * e.model.Height)
+	indexes := e.quantize(display, caps.Palette)
+
+	for j := 0; j < e.model.Height; j++ {
+		for i := 0; i < e.model.Width; i++ {
+			value := caps.value(int(indexes[j * e.model.Width + i]))
+			if i % 2 == 0 {
+				value <<= 4
+			}
+			buffer[j * stride + i / 2] |= value
+		}
+	}
+	return buffer
+}
+
+// quantize maps each pixel of the panel, line by line, to the index of its ink in palette. The pixels of the panel
+// outside of display are white. With Dither, the quantization error is diffused to the neighbouring pixels
+// (Floyd-Steinberg) instead of using the Classifier.
+func (e *EPaper) quantize(display image.Image, palette color.Palette) []uint8 {
+	screen := image.Rect(0, 0, e.model.Width, e.model.Height)
+	bounds := display.Bounds()
+	white := e.classify(color.White, palette)
+
+	if !e.Dither {
+		indexes := make([]uint8, e.model.Width * e.model.Height)
+		for j := 0; j < e.model.Height; j++ {
+			for i := 0; i < e.model.Width; i++ {
+				index := white
+				if p := image.Pt(e.logical(i, j)); p.In(bounds) {
+					index = e.classify(display.At(p.X, p.Y), palette)
+				}
+				indexes[j * e.model.Width + i] = uint8(index)
+			}
+		}
+		return indexes
+	}
+
+	// The error is diffused in the orientation of the panel.
+	src := image.NewRGBA(screen)
+	draw.Draw(src, screen, image.NewUniform(palette[white]), image.Point{}, draw.Src)
+	for j := 0; j < e.model.Height; j++ {
+		for i := 0; i < e.model.Width; i++ {
+			if p := image.Pt(e.logical(i, j)); p.In(bounds) {
+				src.Set(i, j, display.At(p.X, p.Y))
+			}
+		}
+	}
+	dst := image.NewPaletted(screen, palette)
+	draw.FloydSteinberg.Draw(dst, screen, src, image.Point{})
+	return dst.Pix
+}
+
+// Rotation is the orientation of EPaper.Display on the panel.
+type Rotation int
+
+const (
+	// Rotation0 shows EPaper.Display as is (see README.md for the coordinate system).
+	Rotation0 Rotation = iota
+
+	// Rotation90 shows EPaper.Display rotated 90 degrees clockwise (its width is the height of the panel).
+	Rotation90
+
+	// Rotation180 shows EPaper.Display upside down.
+	Rotation180
+
+	// Rotation270 shows EPaper.Display rotated 90 degrees counterclockwise (its width is the height of the panel).
+	Rotation270
+)
+
+// logical returns the coordinates on EPaper.Display of the pixel (x,y) of the panel.
+func (e *EPaper) logical(x, y int) (int, int) {
+	switch e.rotation {
+	case Rotation90:
+		return y, e.model.Width - 1 - x
+	case Rotation180:
+		return e.model.Width - 1 - x, e.model.Height - 1 - y
+	case Rotation270:
+		return e.model.Height - 1 - y, x
+	}
+	return x, y
+}
+
+// toPanel converts a region of EPaper.Display to the coordinates of the panel.
+func (e *EPaper) toPanel(r image.Rectangle) image.Rectangle {
+	w, h := e.model.Width, e.model.Height
+	switch e.rotation {
+	case Rotation90:
+		return image.Rect(w - r.Max.Y, r.Min.X, w - r.Min.Y, r.Max.X)
+	case Rotation180:
+		return image.Rect(w - r.Max.X, h - r.Max.Y, w - r.Min.X, h - r.Min.Y)
+	case Rotation270:
+		return image.Rect(r.Min.Y, h - r.Max.X, r.Max.Y, h - r.Min.X)
+	}
+	return r
+}
+
+// fromPanel converts a region of the panel to the coordinates of EPaper.Display.
+func (e *EPaper) fromPanel(r image.Rectangle) image.Rectangle {
+	w, h := e.model.Width, e.model.Height
+	switch e.rotation {
+	case Rotation90:
+		r = image.Rect(r.Min.Y, w - r.Max.X, r.Max.Y, w - r.Min.X)
+	case Rotation180:
+		r = image.Rect(w - r.Max.X, h - r.Max.Y, w - r.Min.X, h - r.Min.Y)
+	case Rotation270:
+		r = image.Rect(h - r.Max.Y, r.Min.X, h - r.Min.Y, r.Max.X)
+	}
+	// The padding of the lines of the panel is outside of EPaper.Display.
+	return r.Intersect(e.Display.Bounds())
+}
+
+// alignRegion crops r to the screen and widens it so its horizontal bounds match whole bytes of the converted buffer.
+func (e *EPaper) alignRegion(r image.Rectangle) image.Rectangle {
+	r = r.Intersect(image.Rect(0, 0, e.lineWidth * 8, e.model.Height))
+	if r.Empty() {
+		return image.Rectangle{}
+	}
+	r.Min.X &^= 7
+	r.Max.X = (r.Max.X + 7) &^ 7
+	return r
+}
+
+// window extracts the bytes of buffer (as returned by convert) covering the aligned region r.
+func (e *EPaper) window(buffer []byte, r image.Rectangle) []byte {
+	window := make([]byte, 0, r.Dx() / 8 * r.Dy())
+	for j := r.Min.Y; j < r.Max.Y; j++ {
+		line := buffer[j * e.lineWidth:]
+		window = append(window, line[r.Min.X / 8:r.Max.X / 8]...)
+	}
+	return window
+}
+
+// diff returns the smallest region (aligned to whole bytes on X) covering the differences between two converted buffers.
+func (e *EPaper) diff(previous, current []byte) image.Rectangle {
+	format := e.driver.Capabilities().Format
+	stride, pixels := format.stride(e.model.Width), format.pixelsPerByte()
+	plane := stride * e.model.Height
+
+	var r image.Rectangle
+	for i := range current {
+		if previous[i] == current[i] {
+			continue
+		}
+		x, y := (i % plane % stride) * pixels, i % plane / stride
+		r = r.Union(image.Rect(x, y, x + pixels, y + 1))
+	}
+	return r
+}
+
 // Rotate will rotate the image 90 degrees clockwise. Use it before calling convert, because convert will insert the image in the display representation matrix.
 func (e *EPaper) Rotate(img image.Image) image.Image {
 	return transform.Rotate(img, 90.0, &transform.RotationOptions{ResizeBounds: true, Pivot: &image.Point{0, 0}})
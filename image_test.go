@@ -8,13 +8,14 @@ import (
 
 	"github.com/anthonynsimon/bild/paint"
 	"github.com/mcules/go-epaper-lib"
+	"github.com/mcules/go-epaper-lib/epapertest"
 	"periph.io/x/periph/conn/gpio"
 )
 
 func TestAddLayerNoTansparency(t *testing.T) {
 	expectedDisplayResult := []byte{
-		0x13, 0x00, 0xff, 0x00, 0xff, 0x00, 0xff, 0x1e, 0xff, 0x1e, 0xff, 0x1e, 0xff, 0x1e, 0xff, 0x00, 0xff, 0x00,
-		0xff, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0x13, 0x00, 0x3f, 0x00, 0x3f, 0x00, 0x3f, 0x1e, 0x3f, 0x1e, 0x3f, 0x1e, 0x3f, 0x1e, 0x3f, 0x00, 0x3f, 0x00,
+		0x3f, 0x00, 0x3f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
 		0xff, 0xff, 0xff, 0xff, 0xff, 0x12,
 	}
 
@@ -87,12 +88,15 @@ func TestAddLayerNoTansparency(t *testing.T) {
 		t.Fatal(errorMsg)
 	}
 	debug.Reset()
+
+	// Validating what the panel shows.
+	epapertest.AssertGolden(t, e.Snapshot(), "testdata/add_layer_no_tansparency.png")
 }
 
 func TestAddLayerWithTansparency(t *testing.T) {
 	expectedDisplayResult := []byte{
-		0x13, 0x00, 0xff, 0x00, 0xff, 0x00, 0xff, 0x00, 0xff, 0x00, 0xff, 0x00, 0xff, 0x00, 0xff, 0x00, 0xff, 0x00,
-		0xff, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0x13, 0x00, 0x3f, 0x00, 0x3f, 0x00, 0x3f, 0x00, 0x3f, 0x00, 0x3f, 0x00, 0x3f, 0x00, 0x3f, 0x00, 0x3f, 0x00,
+		0x3f, 0x00, 0x3f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
 		0xff, 0xff, 0xff, 0xff, 0xff, 0x12,
 	}
 
@@ -165,6 +169,9 @@ func TestAddLayerWithTansparency(t *testing.T) {
 		t.Fatal(errorMsg)
 	}
 	debug.Reset()
+
+	// Validating what the panel shows.
+	epapertest.AssertGolden(t, e.Snapshot(), "testdata/add_layer_with_tansparency.png")
 }
 
 func TestRotate(t *testing.T) {
@@ -245,4 +252,7 @@ func TestRotate(t *testing.T) {
 		t.Fatal(errorMsg)
 	}
 	debug.Reset()
+
+	// Validating what the panel shows.
+	epapertest.AssertGolden(t, e.Snapshot(), "testdata/rotate.png")
 }
@@ -59,3 +59,227 @@ var (
         0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 	}
 )
+
+// Partial refresh LUTs: a single short phase, so only the changed pixels flash.
+
+var (
+	// Model2in7PartialLutVcomDc is the VCOM LUT used by partial refreshes.
+	Model2in7PartialLutVcomDc []byte = []byte {
+        0x00, 0x00,
+        0x00, 0x19, 0x01, 0x00, 0x00, 0x01,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+    // Model2in7PartialLutWw = R21H
+    Model2in7PartialLutWw []byte = []byte {
+        0x00, 0x19, 0x01, 0x00, 0x00, 0x01,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+    // Model2in7PartialLutBw = R22H    r
+    Model2in7PartialLutBw []byte = []byte {
+        0x80, 0x19, 0x01, 0x00, 0x00, 0x01,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+    // Model2in7PartialLutWb = R23H    w
+    Model2in7PartialLutWb []byte = []byte {
+        0x40, 0x19, 0x01, 0x00, 0x00, 0x01,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+    // Model2in7PartialLutBb = R24H    b
+    Model2in7PartialLutBb []byte = []byte {
+        0x00, 0x19, 0x01, 0x00, 0x00, 0x01,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+)
+
+// Grayscale LUTs: the pair of bits of each pixel in the two planes selects one of the 4 waveforms.
+
+var (
+	// Model2in7GrayLutVcomDc is the VCOM LUT used by the 4-level grayscale refreshes.
+	Model2in7GrayLutVcomDc []byte = []byte {
+        0x00, 0x00,
+        0x00, 0x0a, 0x00, 0x00, 0x00, 0x01,
+        0x60, 0x14, 0x14, 0x00, 0x00, 0x01,
+        0x00, 0x14, 0x00, 0x00, 0x00, 0x01,
+        0x00, 0x13, 0x0a, 0x01, 0x00, 0x01,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+    // Model2in7GrayLutWw = R21H (and R25H), for white pixels
+    Model2in7GrayLutWw []byte = []byte {
+        0x40, 0x0a, 0x00, 0x00, 0x00, 0x01,
+        0x90, 0x14, 0x14, 0x00, 0x00, 0x01,
+        0x10, 0x14, 0x0a, 0x00, 0x00, 0x01,
+        0xa0, 0x13, 0x01, 0x00, 0x00, 0x01,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+    // Model2in7GrayLutBw = R22H, for dark gray pixels
+    Model2in7GrayLutBw []byte = []byte {
+        0x40, 0x0a, 0x00, 0x00, 0x00, 0x01,
+        0x90, 0x14, 0x14, 0x00, 0x00, 0x01,
+        0x00, 0x14, 0x0a, 0x00, 0x00, 0x01,
+        0x99, 0x0c, 0x01, 0x03, 0x04, 0x01,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+    // Model2in7GrayLutWb = R23H, for light gray pixels
+    Model2in7GrayLutWb []byte = []byte {
+        0x40, 0x0a, 0x00, 0x00, 0x00, 0x01,
+        0x90, 0x14, 0x14, 0x00, 0x00, 0x01,
+        0x00, 0x14, 0x0a, 0x00, 0x00, 0x01,
+        0x99, 0x0b, 0x04, 0x04, 0x01, 0x01,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+    // Model2in7GrayLutBb = R24H, for black pixels
+    Model2in7GrayLutBb []byte = []byte {
+        0x80, 0x0a, 0x00, 0x00, 0x00, 0x01,
+        0x90, 0x14, 0x14, 0x00, 0x00, 0x01,
+        0x20, 0x14, 0x0a, 0x00, 0x00, 0x01,
+        0x50, 0x13, 0x01, 0x00, 0x00, 0x01,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+)
+
+// 4.2 inches LUTs (UC8176 controller).
+
+var (
+	// Model4in2LutVcomDc = R20H
+	Model4in2LutVcomDc []byte = []byte {
+        0x00, 0x17, 0x00, 0x00, 0x00, 0x02,
+        0x00, 0x17, 0x17, 0x00, 0x00, 0x02,
+        0x00, 0x0A, 0x01, 0x00, 0x00, 0x01,
+        0x00, 0x0E, 0x0E, 0x00, 0x00, 0x02,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00,
+	}
+
+    // Model4in2LutWw = R21H
+    Model4in2LutWw []byte = []byte {
+        0x40, 0x17, 0x00, 0x00, 0x00, 0x02,
+        0x90, 0x17, 0x17, 0x00, 0x00, 0x02,
+        0x40, 0x0A, 0x01, 0x00, 0x00, 0x01,
+        0xA0, 0x0E, 0x0E, 0x00, 0x00, 0x02,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+    // Model4in2LutBw = R22H    r
+    Model4in2LutBw []byte = []byte {
+        0x40, 0x17, 0x00, 0x00, 0x00, 0x02,
+        0x90, 0x17, 0x17, 0x00, 0x00, 0x02,
+        0x40, 0x0A, 0x01, 0x00, 0x00, 0x01,
+        0xA0, 0x0E, 0x0E, 0x00, 0x00, 0x02,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+    // Model4in2LutWb = R23H    w
+    Model4in2LutWb []byte = []byte {
+        0x80, 0x17, 0x00, 0x00, 0x00, 0x02,
+        0x90, 0x17, 0x17, 0x00, 0x00, 0x02,
+        0x80, 0x0A, 0x01, 0x00, 0x00, 0x01,
+        0x50, 0x0E, 0x0E, 0x00, 0x00, 0x02,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+    // Model4in2LutBb = R24H    b
+    Model4in2LutBb []byte = []byte {
+        0x80, 0x17, 0x00, 0x00, 0x00, 0x02,
+        0x90, 0x17, 0x17, 0x00, 0x00, 0x02,
+        0x80, 0x0A, 0x01, 0x00, 0x00, 0x01,
+        0x50, 0x0E, 0x0E, 0x00, 0x00, 0x02,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+        0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+)
+
+// Built-in waveforms used by the drivers. Their LUTs are copies of the exported ones, so changing those has no effect.
+var (
+	waveform2in7 = Waveform{
+		VCOM: cloneLut(Model2in7LutVcomDc),
+		WW: cloneLut(Model2in7LutWw),
+		BW: cloneLut(Model2in7LutBw),
+		WB: cloneLut(Model2in7LutWb),
+		BB: cloneLut(Model2in7LutBb),
+	}
+	waveform2in7Partial = Waveform{
+		VCOM: cloneLut(Model2in7PartialLutVcomDc),
+		WW: cloneLut(Model2in7PartialLutWw),
+		BW: cloneLut(Model2in7PartialLutBw),
+		WB: cloneLut(Model2in7PartialLutWb),
+		BB: cloneLut(Model2in7PartialLutBb),
+	}
+	waveform2in7Gray = Waveform{
+		VCOM: cloneLut(Model2in7GrayLutVcomDc),
+		WW: cloneLut(Model2in7GrayLutWw),
+		BW: cloneLut(Model2in7GrayLutBw),
+		WB: cloneLut(Model2in7GrayLutWb),
+		BB: cloneLut(Model2in7GrayLutBb),
+	}
+	waveform4in2 = Waveform{
+		VCOM: cloneLut(Model4in2LutVcomDc),
+		WW: cloneLut(Model4in2LutWw),
+		BW: cloneLut(Model4in2LutBw),
+		WB: cloneLut(Model4in2LutWb),
+		BB: cloneLut(Model4in2LutBb),
+	}
+)
+
+// Waveform2in7 returns the waveform of the full refreshes of the 2.7 inches black-and-white panel, to be used as a
+// base for custom ones (see EPaper.SetWaveform). It is a copy: changing it does not change the built-in one.
+func Waveform2in7() Waveform {
+	return waveform2in7.clone()
+}
+
+// Waveform4in2 returns the waveform of the full refreshes of the 4.2 inches black-and-white panel, to be used as a
+// base for custom ones (see EPaper.SetWaveform). It is a copy: changing it does not change the built-in one.
+func Waveform4in2() Waveform {
+	return waveform4in2.clone()
+}
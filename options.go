@@ -0,0 +1,106 @@
+package epaper
+
+import (
+	"io"
+	"time"
+
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+)
+
+// Option changes a setting of the display created by Open().
+type Option func(*Config)
+
+// Open creates a new instance of EPaper. Without options, it uses the e-Paper HAT module on the first SPI port.
+func Open(model Model, opts ...Option) (*EPaper, error) {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return NewWithConfig(model, config)
+}
+
+// WithPins sets the names of the pins where DC, CS, RST and BUSY are connected.
+func WithPins(dcPin, csPin, rstPin, busyPin string) Option {
+	return func(c *Config) {
+		c.DataCommandPin = dcPin
+		c.ChipSelectionPin = csPin
+		c.ResetPin = rstPin
+		c.BusyPin = busyPin
+	}
+}
+
+// WithPinIO uses the pins supplied instead of looking them up by name. Nil pins are still looked up by name.
+func WithPinIO(dc, cs, rst, busy gpio.PinIO) Option {
+	return func(c *Config) {
+		c.DataCommand = dc
+		c.ChipSelection = cs
+		c.Reset = rst
+		c.Busy = busy
+	}
+}
+
+// WithPort uses the SPI port supplied instead of opening one by name. The caller keeps the ownership of port.
+func WithPort(port spi.Port) Option {
+	return func(c *Config) {
+		c.Port = port
+	}
+}
+
+// WithSPIPort sets the name of the SPI port (e.g. "SPI0.0" or "SPI1.0").
+func WithSPIPort(name string) Option {
+	return func(c *Config) {
+		c.SPIPort = name
+	}
+}
+
+// WithSPIFrequency sets the SPI clock speed.
+func WithSPIFrequency(f physic.Frequency) Option {
+	return func(c *Config) {
+		c.SPIFrequency = f
+	}
+}
+
+// WithSPIMode sets the clock polarity and phase of the SPI bus.
+func WithSPIMode(mode spi.Mode) Option {
+	return func(c *Config) {
+		c.SPIMode = mode
+	}
+}
+
+// WithSimulation replaces the hardware by fakes, writing the bytes sent to debug (which can be nil).
+func WithSimulation(debug io.Writer) Option {
+	return func(c *Config) {
+		c.Simulation = true
+		c.Debug = debug
+	}
+}
+
+// WithLogger traces the commands sent and the time spent waiting for the display.
+func WithLogger(l Logger) Option {
+	return func(c *Config) {
+		c.Logger = l
+	}
+}
+
+// WithSleepOnClose tells if EPaper.Close() puts the display in deep sleep (the default).
+func WithSleepOnClose(sleep bool) Option {
+	return func(c *Config) {
+		c.SleepOnClose = sleep
+	}
+}
+
+// WithBusyTimeout sets the longest time to wait for the display to finish an operation.
+func WithBusyTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.BusyTimeout = d
+	}
+}
+
+// WithRotation sets the orientation of EPaper.Display on the panel.
+func WithRotation(r Rotation) Option {
+	return func(c *Config) {
+		c.Rotation = r
+	}
+}
@@ -0,0 +1,277 @@
+package epaper_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mcules/go-epaper-lib"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/spi/spitest"
+)
+
+// testLogger keeps the messages logged.
+type testLogger struct {
+	lines []string
+}
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestOpenWithOptions(t *testing.T) {
+	logger := &testLogger{}
+	e, err := epaper.Open(epaper.Model{Width: 16, Height: 20},
+		epaper.WithSimulation(new(bytes.Buffer)),
+		epaper.WithBusyTimeout(50*time.Millisecond),
+		epaper.WithLogger(logger),
+		epaper.WithRotation(epaper.Rotation90),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The display is rotated, so its width is the height of the panel.
+	if got := e.Display.Bounds(); got != image.Rect(0, 0, 20, 16) {
+		t.Fatalf("Expected Display bounds (0,0)-(20,16), found %v", got)
+	}
+
+	// BUSY stays low: the configured timeout must be used.
+	if err := e.Init(); !errors.Is(err, epaper.ErrBusyTimeout) {
+		t.Fatalf("Expected ErrBusyTimeout, found %v", err)
+	}
+	if len(logger.lines) == 0 || !strings.HasPrefix(logger.lines[0], "epaper: command 0x01") {
+		t.Fatalf("Expected the commands to be logged, found %q", logger.lines)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	for _, test := range []struct {
+		rotation epaper.Rotation
+		logical  image.Point
+		panel    image.Point
+	}{
+		{epaper.Rotation0, image.Point{X: 1, Y: 2}, image.Point{X: 1, Y: 2}},
+		{epaper.Rotation90, image.Point{X: 1, Y: 2}, image.Point{X: 13, Y: 1}},
+		{epaper.Rotation180, image.Point{X: 1, Y: 2}, image.Point{X: 14, Y: 17}},
+		{epaper.Rotation270, image.Point{X: 1, Y: 2}, image.Point{X: 2, Y: 18}},
+	} {
+		// Create a dummy "epaper"
+		// (to create a real one, use the example source code, this won't work!)
+		e, err := epaper.Open(epaper.Model{Width: 16, Height: 20},
+			epaper.WithSimulation(new(bytes.Buffer)),
+			epaper.WithRotation(test.rotation),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Forcing the BUSY to High to avoid being blocked because of WaitUntilIdle().
+		// Do not do this on real cases!
+		e.Busy.Out(gpio.High)
+
+		if err := e.Init(); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.ClearScreen(); err != nil {
+			t.Fatal(err)
+		}
+
+		e.Display.Set(test.logical.X, test.logical.Y, color.Black)
+		if err := e.PrintDisplay(); err != nil {
+			t.Fatal(err)
+		}
+
+		snapshot := e.Snapshot()
+		if n := countBlack(snapshot, snapshot.Bounds()); n != 1 {
+			t.Fatalf("Rotation %d: expected 1 black pixel, found %d", test.rotation, n)
+		}
+		if n := countBlack(snapshot, image.Rectangle{Min: test.panel, Max: test.panel.Add(image.Point{X: 1, Y: 1})}); n != 1 {
+			t.Fatalf("Rotation %d: expected the black pixel at %v on the panel", test.rotation, test.panel)
+		}
+
+		// Regions are converted to the coordinates of the panel (and back).
+		e.Display.Set(test.logical.X, test.logical.Y, color.White)
+		region := e.DirtyRegion()
+		if !test.logical.In(region) {
+			t.Fatalf("Rotation %d: expected the dirty region %v to contain %v", test.rotation, region, test.logical)
+		}
+		if err := e.PrintRegion(region); err != nil {
+			t.Fatal(err)
+		}
+		if n := countBlack(e.Snapshot(), snapshot.Bounds()); n != 0 {
+			t.Fatalf("Rotation %d: expected no black pixel after the partial refresh, found %d", test.rotation, n)
+		}
+	}
+}
+
+// busyPin is a fake BUSY pin without edge detection, going idle after being read a few times.
+type busyPin struct {
+	gpiotest.Pin
+	reads int
+}
+
+func (p *busyPin) In(pull gpio.Pull, edge gpio.Edge) error {
+	if edge != gpio.NoEdge {
+		return errors.New("edges not supported")
+	}
+	return nil
+}
+
+func (p *busyPin) Read() gpio.Level {
+	p.reads++
+	return p.reads > 3
+}
+
+func TestOpenWithSuppliedHardware(t *testing.T) {
+	dc := &gpiotest.Pin{N: "DC"}
+	cs := &gpiotest.Pin{N: "CS"}
+	rst := &gpiotest.Pin{N: "RST"}
+	busy := &busyPin{}
+	debug := new(bytes.Buffer)
+	port := spitest.NewRecordRaw(debug)
+
+	e, err := epaper.Open(ModelSim, epaper.WithPinIO(dc, cs, rst, busy), epaper.WithPort(port))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+
+	// BUSY was polled until it went high.
+	if busy.reads != 4 {
+		t.Fatalf("Expected BUSY to be read 4 times, found %d", busy.reads)
+	}
+	errorMsg := validateByteSlice(debug.Bytes(), []byte{0x02, 0x07, 0xa5}, "Sleep function")
+	if len(errorMsg) > 0 {
+		t.Fatal(errorMsg)
+	}
+	if cs.Read() != gpio.High || dc.Read() != gpio.High {
+		t.Fatal("Expected the supplied DC and CS pins to be driven")
+	}
+}
+
+// closingPort counts the calls to Close.
+type closingPort struct {
+	*spitest.RecordRaw
+	closed int
+}
+
+func (p *closingPort) Close() error {
+	p.closed++
+	return nil
+}
+
+func TestClose(t *testing.T) {
+	dc := &gpiotest.Pin{N: "DC"}
+	cs := &gpiotest.Pin{N: "CS"}
+	rst := &gpiotest.Pin{N: "RST"}
+	busy := &gpiotest.Pin{N: "BUSY"}
+	debug := new(bytes.Buffer)
+	port := &closingPort{RecordRaw: spitest.NewRecordRaw(debug)}
+
+	e, err := epaper.Open(ModelSim, epaper.WithPinIO(dc, cs, rst, busy), epaper.WithPort(port))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Forcing the BUSY to High to avoid being blocked because of WaitUntilIdle().
+	// Do not do this on real cases!
+	e.Busy.Out(gpio.High)
+
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	debug.Reset()
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	errorMsg := validateByteSlice(debug.Bytes(), []byte{0x02, 0x07, 0xa5}, "Close function")
+	if len(errorMsg) > 0 {
+		t.Fatal(errorMsg)
+	}
+	if rst.Read() != gpio.Low || dc.Read() != gpio.Low || cs.Read() != gpio.High {
+		t.Fatal("Expected RST and DC low and CS high after Close")
+	}
+	if port.closed != 0 {
+		t.Fatal("Expected the port supplied by the caller to stay open")
+	}
+
+	// Closing again does nothing, using the display fails.
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Init(); !errors.Is(err, epaper.ErrClosed) {
+		t.Fatalf("Expected ErrClosed, found %v", err)
+	}
+	if err := e.Sleep(); !errors.Is(err, epaper.ErrClosed) {
+		t.Fatalf("Expected ErrClosed, found %v", err)
+	}
+}
+
+// edgePin is a fake BUSY pin detecting edges, recording the last one configured.
+type edgePin struct {
+	gpiotest.Pin
+	edge gpio.Edge
+}
+
+func (p *edgePin) In(pull gpio.Pull, edge gpio.Edge) error {
+	p.edge = edge
+	return nil
+}
+
+func TestCloseStopsEdgeDetection(t *testing.T) {
+	busy := &edgePin{Pin: gpiotest.Pin{N: "BUSY", L: gpio.High}}
+	pins := []gpio.PinIO{&gpiotest.Pin{N: "DC"}, &gpiotest.Pin{N: "CS"}, &gpiotest.Pin{N: "RST"}, busy}
+	e, err := epaper.Open(ModelSim, epaper.WithPinIO(pins[0], pins[1], pins[2], pins[3]),
+		epaper.WithPort(spitest.NewRecordRaw(new(bytes.Buffer))), epaper.WithSleepOnClose(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if busy.edge != gpio.BothEdges {
+		t.Fatalf("Expected BUSY to detect both edges, found %s", busy.edge)
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if busy.edge != gpio.NoEdge {
+		t.Fatalf("Expected the edge detection of BUSY to be stopped after Close, found %s", busy.edge)
+	}
+}
+
+func TestCloseWithoutSleep(t *testing.T) {
+	debug := new(bytes.Buffer)
+	e, err := epaper.Open(ModelSim, epaper.WithSimulation(debug), epaper.WithSleepOnClose(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Forcing the BUSY to High to avoid being blocked because of WaitUntilIdle().
+	// Do not do this on real cases!
+	e.Busy.Out(gpio.High)
+
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	debug.Reset()
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if debug.Len() != 0 {
+		t.Fatalf("Expected nothing sent to the display, found %v", debug.Bytes())
+	}
+	if e.Emulator().Sleeping() {
+		t.Fatal("Expected the panel not to be put in deep sleep")
+	}
+}
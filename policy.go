@@ -0,0 +1,51 @@
+package epaper
+
+import (
+	"image"
+	"time"
+)
+
+// RefreshPolicy limits the ghosting accumulated by partial refreshes, forcing a full refresh from time to time.
+// The zero value never forces a full refresh.
+type RefreshPolicy struct {
+	// MaxPartialRefreshes is the number of partial refreshes allowed between two full refreshes (0: no limit).
+	MaxPartialRefreshes int
+
+	// MaxPartialAge is the longest time partial refreshes are used after a full refresh (0: no limit).
+	MaxPartialAge time.Duration
+
+	// ClearFlash flashes the screen white before each forced full refresh, which removes more ghosting.
+	ClearFlash bool
+
+	// Now returns the current time (time.Now if nil). Tests can replace it to simulate the passing of time.
+	Now func() time.Time
+
+	// OnRefresh is called after every successful refresh, with the region refreshed (in the coordinates of
+	// EPaper.Display) and whether it was partial. It is called once the operation releases the display, so it can call
+	// the methods of EPaper.
+	OnRefresh func(r image.Rectangle, partial bool)
+}
+
+// refresh is a refresh waiting to be notified to RefreshPolicy.OnRefresh.
+type refresh struct {
+	r       image.Rectangle
+	partial bool
+}
+
+func (p *RefreshPolicy) now() time.Time {
+	if p.Now != nil {
+		return p.Now()
+	}
+	return time.Now()
+}
+
+// fullRefreshDue tells if the next refresh must be a full one, given the partial refreshes done since lastFull.
+func (p *RefreshPolicy) fullRefreshDue(partials int, lastFull time.Time) bool {
+	if partials == 0 {
+		return false
+	}
+	if p.MaxPartialRefreshes > 0 && partials >= p.MaxPartialRefreshes {
+		return true
+	}
+	return p.MaxPartialAge > 0 && p.now().Sub(lastFull) >= p.MaxPartialAge
+}
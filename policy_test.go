@@ -0,0 +1,149 @@
+package epaper_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+	"time"
+
+	"github.com/mcules/go-epaper-lib"
+	"periph.io/x/periph/conn/gpio"
+)
+
+// newPolicyTestEPaper creates a dummy, initialized and cleared, "epaper" recording the refreshes made.
+func newPolicyTestEPaper(t *testing.T, policy epaper.RefreshPolicy) (*epaper.EPaper, *bytes.Buffer, *[]bool) {
+	// Create a dummy "epaper"
+	// (to create a real one, use the example source code, this won't work!)
+	debug := new(bytes.Buffer)
+	e, err := epaper.NewCustom("", "", "", "", ModelSim, true, debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refreshes := []bool{}
+	policy.OnRefresh = func(r image.Rectangle, partial bool) {
+		refreshes = append(refreshes, partial)
+	}
+	e.Policy = policy
+
+	// Forcing the BUSY to High to avoid being blocked because of WaitUntilIdle().
+	// Do not do this on real cases!
+	e.Busy.Out(gpio.High)
+
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.ClearScreen(); err != nil {
+		t.Fatal(err)
+	}
+	debug.Reset()
+	refreshes = refreshes[:0]
+
+	return e, debug, &refreshes
+}
+
+func TestPolicyMaxPartialRefreshes(t *testing.T) {
+	e, debug, refreshes := newPolicyTestEPaper(t, epaper.RefreshPolicy{MaxPartialRefreshes: 2, ClearFlash: true})
+
+	for i := 0; i < 4; i++ {
+		draw.Draw(e.Display, image.Rect(i, 0, i+1, 1), image.NewUniform(color.Black), image.Point{}, draw.Src)
+		debug.Reset()
+		if err := e.PrintRegion(image.Rect(i, 0, i+1, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []bool{true, true, false, true}
+	if len(*refreshes) != len(expected) {
+		t.Fatalf("Expected %d refreshes, found %v", len(expected), *refreshes)
+	}
+	for i := range expected {
+		if (*refreshes)[i] != expected[i] {
+			t.Fatalf("Refresh %d: expected partial = %t, found %v", i, expected[i], *refreshes)
+		}
+	}
+
+	// The full refresh reset the count, so the last refresh is partial again (no clearing flash).
+	if debug.Bytes()[0] == 0x10 {
+		t.Fatalf("Expected a partial refresh, found %v", debug.Bytes())
+	}
+}
+
+func TestPolicyClearFlash(t *testing.T) {
+	e, debug, _ := newPolicyTestEPaper(t, epaper.RefreshPolicy{MaxPartialRefreshes: 1, ClearFlash: true})
+
+	if err := e.PrintRegion(image.Rect(0, 0, 8, 1)); err != nil {
+		t.Fatal(err)
+	}
+	debug.Reset()
+
+	if err := e.PrintRegion(image.Rect(0, 0, 8, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Clear (0x10 and 0x13 with 40 bytes each), refresh (after restoring the full LUTs), then the frame (0x13) and refresh.
+	out := debug.Bytes()
+	if out[0] != 0x10 || out[41] != 0x13 || !bytes.HasSuffix(out[:len(out)-42], []byte{0x12}) || out[len(out)-42] != 0x13 {
+		t.Fatalf("Expected a clearing flash before the full refresh, found %v", debug.Bytes())
+	}
+}
+
+func TestPolicyMaxPartialAge(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	e, _, refreshes := newPolicyTestEPaper(t, epaper.RefreshPolicy{
+		MaxPartialAge: 10 * time.Minute,
+		Now:           func() time.Time { return now },
+	})
+	e.PartialThreshold = 0.5
+
+	for i := 0; i < 3; i++ {
+		draw.Draw(e.Display, image.Rect(i, 0, i+1, 1), image.NewUniform(color.Black), image.Point{}, draw.Src)
+		if err := e.PrintDisplay(); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(6 * time.Minute)
+	}
+
+	expected := []bool{true, true, false}
+	if len(*refreshes) != len(expected) {
+		t.Fatalf("Expected %d refreshes, found %v", len(expected), *refreshes)
+	}
+	for i := range expected {
+		if (*refreshes)[i] != expected[i] {
+			t.Fatalf("Refresh %d: expected partial = %t, found %v", i, expected[i], *refreshes)
+		}
+	}
+}
+
+func TestPolicyOnRefreshCallback(t *testing.T) {
+	e, _, _ := newPolicyTestEPaper(t, epaper.RefreshPolicy{})
+
+	// The callback can call EPaper: the display is released before it runs.
+	called := false
+	e.Policy.OnRefresh = func(r image.Rectangle, partial bool) {
+		if r != e.Display.Bounds() {
+			t.Errorf("Expected the region of the display to be refreshed, found %v", r)
+		}
+		if err := e.SetRefreshMode(epaper.ModeFull); err != nil {
+			t.Error(err)
+		}
+		called = true
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- e.PrintDisplay()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected OnRefresh to be able to call EPaper")
+	}
+	if !called {
+		t.Fatal("Expected OnRefresh to be called")
+	}
+}
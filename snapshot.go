@@ -0,0 +1,56 @@
+package epaper
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+// Snapshot returns what the panel currently shows. It is only available in simulation mode, otherwise it returns nil.
+func (e *EPaper) Snapshot() image.Image {
+	if e.emulator == nil {
+		return nil
+	}
+	return e.emulator.Image()
+}
+
+// RecordSnapshots saves a PNG snapshot of the panel in dir after each refresh (frame-0001.png, frame-0002.png, ...).
+// It is only available in simulation mode. An empty dir stops the recording.
+func (e *EPaper) RecordSnapshots(dir string) error {
+	if dir != "" && e.emulator == nil {
+		return ErrUnsupported
+	}
+	e.mu.Lock()
+	defer e.unlock()
+	e.snapshotDir = dir
+	return nil
+}
+
+// SavePNG writes img to the PNG file path.
+func SavePNG(img image.Image, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// refreshed is called after each successful refresh of the region r.
+func (e *EPaper) refreshed(r image.Rectangle, partial bool) error {
+	e.logf("epaper: refreshed %v (partial: %t)", r, partial)
+	if e.Policy.OnRefresh != nil {
+		e.refreshes = append(e.refreshes, refresh{e.fromPanel(r), partial})
+	}
+
+	if e.snapshotDir == "" {
+		return nil
+	}
+	e.snapshots++
+	return SavePNG(e.Snapshot(), filepath.Join(e.snapshotDir, fmt.Sprintf("frame-%04d.png", e.snapshots)))
+}
@@ -0,0 +1,239 @@
+package epaper
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+)
+
+// Commands of the SSD1680 family of controllers.
+const (
+	ssdDriverOutputControl   byte = 0x01
+	ssdGateVoltage           byte = 0x03
+	ssdSourceVoltage         byte = 0x04
+	ssdDeepSleep             byte = 0x10
+	ssdDataEntryMode         byte = 0x11
+	ssdSoftReset             byte = 0x12
+	ssdTemperatureSensor     byte = 0x18
+	ssdWriteTemperature      byte = 0x1A
+	ssdMasterActivation      byte = 0x20
+	ssdUpdateControl1        byte = 0x21
+	ssdUpdateControl2        byte = 0x22
+	ssdWriteVCOM             byte = 0x2C
+	ssdWriteRAMBlack         byte = 0x24
+	ssdWriteRAMRed           byte = 0x26
+	ssdWriteLUT              byte = 0x32
+	ssdBorderWaveform        byte = 0x3C
+	ssdEndOption             byte = 0x3F
+	ssdSetRAMXAddress        byte = 0x44
+	ssdSetRAMYAddress        byte = 0x45
+	ssdSetRAMXAddressCounter byte = 0x4E
+	ssdSetRAMYAddressCounter byte = 0x4F
+)
+
+// Sequences of the display update control 2 command (0x22), started by the master activation (0x20).
+const (
+	ssdUpdateFull      byte = 0xF7 // Load the temperature and the LUT, display with mode 1
+	ssdUpdateFast      byte = 0xC7 // Display with mode 1, using the LUT already loaded
+	ssdUpdatePartial   byte = 0xFF // Load the temperature and the LUT, display with mode 2
+	ssdUpdateLoadLut   byte = 0x91 // Load the LUT for the temperature written, without displaying
+	ssdDisplayBit      byte = 0x04 // The sequence updates the panel
+	ssdDisplayMode2Bit byte = 0x08 // The sequence uses the display mode 2 (partial update)
+)
+
+// ssd1680 drives the panels with a controller of the SSD1680 family, using its built-in waveforms. The tri-color panels
+// (with a Model.Color) only support full refreshes.
+type ssd1680 struct {
+	bus     Bus
+	model   Model
+	mode    RefreshMode
+	partial bool // The border is set up for partial refreshes
+
+	waveform *Waveform // Custom waveform of the full and fast refreshes (nil: built-in)
+}
+
+// newSsd1680 creates the driver for a panel with a controller of the SSD1680 family.
+func newSsd1680(b Bus, m Model) Driver {
+	return &ssd1680{bus: b, model: m}
+}
+
+func (d *ssd1680) Capabilities() Capabilities {
+	if d.model.Color != nil {
+		return Capabilities{
+			Palette:    color.Palette{color.Black, color.White, d.model.Color},
+			Controller: ControllerSSD16xx,
+			Format:     FormatPlanes,
+		}
+	}
+	return Capabilities{
+		Palette:    color.Palette{color.Black, color.White},
+		Controller: ControllerSSD16xx,
+		Modes:      []RefreshMode{ModeFast, ModePartial},
+	}
+}
+
+func (d *ssd1680) Init(ctx context.Context) error {
+	if err := d.bus.Reset(); err != nil {
+		return err
+	}
+	if err := d.bus.WaitUntilIdle(ctx); err != nil {
+		return err
+	}
+	if err := d.bus.Send(ssdSoftReset, nil); err != nil {
+		return err
+	}
+	if err := d.bus.WaitUntilIdle(ctx); err != nil {
+		return err
+	}
+
+	lines := d.model.Height - 1
+	err := sendSequence(d.bus, []command{
+		{ssdDriverOutputControl, []byte{byte(lines), byte(lines >> 8), 0x00}},
+		{ssdDataEntryMode, []byte{0x03}}, // X and Y increment
+		{ssdBorderWaveform, []byte{0x05}},
+		{ssdUpdateControl1, []byte{0x00, 0x80}},
+		{ssdTemperatureSensor, []byte{0x80}}, // Internal sensor
+	})
+	if err != nil {
+		return err
+	}
+	if err := d.setWindow(d.screen()); err != nil {
+		return err
+	}
+	d.partial = false
+	return d.bus.WaitUntilIdle(ctx)
+}
+
+func (d *ssd1680) SetMode(m RefreshMode) error {
+	if m != ModeFull && (m != ModeFast || d.model.Color != nil) {
+		return ErrUnsupported
+	}
+	d.mode = m
+	return nil
+}
+
+func (d *ssd1680) Clear(ctx context.Context) error {
+	white := bytes.Repeat([]byte{0xFF}, (d.model.Width+7)/8*d.model.Height)
+	if d.model.Color != nil {
+		return d.writeRAMs(white, make([]byte, len(white)))
+	}
+	return d.writeRAMs(white, white)
+}
+
+func (d *ssd1680) WriteFrame(ctx context.Context, frame []byte) error {
+	if d.model.Color != nil {
+		// A bit of the red RAM is set for a pixel of the third color.
+		n := len(frame) / 2
+		red := make([]byte, n)
+		for i, b := range frame[n:] {
+			red[i] = ^b
+		}
+		return d.writeRAMs(frame[:n], red)
+	}
+	// The frame goes in both RAMs, so the next partial refresh starts from it.
+	return d.writeRAMs(frame, frame)
+}
+
+// writeRAMs writes the black and the red RAMs of the whole screen.
+func (d *ssd1680) writeRAMs(black, red []byte) error {
+	for _, ram := range []command{{ssdWriteRAMBlack, black}, {ssdWriteRAMRed, red}} {
+		if err := d.setWindow(d.screen()); err != nil {
+			return err
+		}
+		if err := d.bus.Send(ram.cmd, ram.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *ssd1680) Refresh(ctx context.Context) error {
+	if d.partial {
+		if err := d.bus.Send(ssdBorderWaveform, []byte{0x05}); err != nil {
+			return err
+		}
+		d.partial = false
+	}
+
+	if d.waveform != nil {
+		// The LUT is loaded again each time: the partial refreshes replace it with the built-in one.
+		if err := sendSequence(d.bus, d.waveform.ssdCommands()); err != nil {
+			return err
+		}
+		return d.update(ctx, ssdUpdateFast)
+	}
+
+	if d.mode == ModeFast {
+		// Forcing a high temperature loads the shorter waveform.
+		err := sendSequence(d.bus, []command{
+			{ssdWriteTemperature, []byte{0x64, 0x00}},
+			{ssdUpdateControl2, []byte{ssdUpdateLoadLut}},
+			{ssdMasterActivation, nil},
+		})
+		if err != nil {
+			return err
+		}
+		if err := d.bus.WaitUntilIdle(ctx); err != nil {
+			return err
+		}
+		return d.update(ctx, ssdUpdateFast)
+	}
+	return d.update(ctx, ssdUpdateFull)
+}
+
+func (d *ssd1680) SetWaveform(w *Waveform) error {
+	d.waveform = w
+	return nil
+}
+
+func (d *ssd1680) RefreshRegion(ctx context.Context, r image.Rectangle, window []byte) error {
+	if !d.partial {
+		if err := d.bus.Send(ssdBorderWaveform, []byte{0x80}); err != nil {
+			return err
+		}
+		d.partial = true
+	}
+
+	if err := d.setWindow(r); err != nil {
+		return err
+	}
+	if err := d.bus.Send(ssdWriteRAMBlack, window); err != nil {
+		return err
+	}
+	return d.update(ctx, ssdUpdatePartial)
+}
+
+func (d *ssd1680) Sleep(ctx context.Context) error {
+	return d.bus.Send(ssdDeepSleep, []byte{0x01})
+}
+
+// update runs the display update sequence and waits for its end.
+func (d *ssd1680) update(ctx context.Context, sequence byte) error {
+	err := sendSequence(d.bus, []command{
+		{ssdUpdateControl2, []byte{sequence}},
+		{ssdMasterActivation, nil},
+	})
+	if err != nil {
+		return err
+	}
+	return d.bus.WaitUntilIdle(ctx)
+}
+
+// setWindow restricts the RAM writes to r, whose horizontal bounds are multiples of 8, and moves the address counters
+// to its top-left corner.
+func (d *ssd1680) setWindow(r image.Rectangle) error {
+	x0, x1 := r.Min.X/8, (r.Max.X-1)/8
+	y0, y1 := r.Min.Y, r.Max.Y-1
+	return sendSequence(d.bus, []command{
+		{ssdSetRAMXAddress, []byte{byte(x0), byte(x1)}},
+		{ssdSetRAMYAddress, []byte{byte(y0), byte(y0 >> 8), byte(y1), byte(y1 >> 8)}},
+		{ssdSetRAMXAddressCounter, []byte{byte(x0)}},
+		{ssdSetRAMYAddressCounter, []byte{byte(y0), byte(y0 >> 8)}},
+	})
+}
+
+// screen returns the whole RAM area of the panel.
+func (d *ssd1680) screen() image.Rectangle {
+	return image.Rect(0, 0, (d.model.Width+7)/8*8, d.model.Height)
+}
@@ -0,0 +1,209 @@
+package epaper_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/mcules/go-epaper-lib"
+	"periph.io/x/periph/conn/gpio"
+)
+
+var ExpectedSSD1680InitResult = []byte{
+	0x12,
+	0x01, 0x07, 0x01, 0x00,
+	0x11, 0x03,
+	0x3c, 0x05,
+	0x21, 0x00, 0x80,
+	0x18, 0x80,
+	0x44, 0x00, 0x15,
+	0x45, 0x00, 0x00, 0x07, 0x01,
+	0x4e, 0x00,
+	0x4f, 0x00, 0x00,
+}
+
+// newSSD1680TestEPaper creates a dummy, initialized and cleared, 2.7 inches V2 "epaper".
+func newSSD1680TestEPaper(t *testing.T) (*epaper.EPaper, *bytes.Buffer) {
+	// BUSY is high while the controller is busy.
+	e, debug := newDriverTestEPaper(t, epaper.Model2in7bwV2, gpio.Low)
+	errorMsg := validateByteSlice(debug.Bytes(), ExpectedSSD1680InitResult, "Init function")
+	if len(errorMsg) > 0 {
+		t.Fatal(errorMsg)
+	}
+	if err := e.ClearScreen(); err != nil {
+		t.Fatal(err)
+	}
+	debug.Reset()
+	return e, debug
+}
+
+func TestSSD1680FullRefresh(t *testing.T) {
+	e, debug := newSSD1680TestEPaper(t)
+	em := e.Emulator()
+
+	draw.Draw(e.Display, image.Rect(0, 0, 16, 4), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+	if n := countBlack(em.Image(), em.Image().Bounds()); n != 64 {
+		t.Fatalf("Expected 64 black pixels on screen, found %d", n)
+	}
+	if full, partial := em.Refreshes(); full != 2 || partial != 0 {
+		t.Fatalf("Expected 2 full refreshes, found %d full and %d partial", full, partial)
+	}
+	if !bytes.HasSuffix(debug.Bytes(), []byte{0x22, 0xf7, 0x20}) {
+		t.Fatal("Expected a full update sequence")
+	}
+
+	// Fast mode: the shorter waveform is loaded before the update.
+	if err := e.SetRefreshMode(epaper.ModeFast); err != nil {
+		t.Fatal(err)
+	}
+	draw.Draw(e.Display, image.Rect(0, 0, 16, 4), image.NewUniform(color.White), image.Point{}, draw.Src)
+	debug.Reset()
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(debug.Bytes(), []byte{0x1a, 0x64, 0x00, 0x22, 0x91, 0x20, 0x22, 0xc7, 0x20}) {
+		t.Fatalf("Expected a fast update sequence, found %v", debug.Bytes()[debug.Len()-9:])
+	}
+	if n := countBlack(em.Image(), em.Image().Bounds()); n != 0 {
+		t.Fatalf("Expected a white screen, found %d black pixels", n)
+	}
+}
+
+func TestSSD1680PartialRefresh(t *testing.T) {
+	e, debug := newSSD1680TestEPaper(t)
+	em := e.Emulator()
+
+	draw.Draw(e.Display, image.Rect(8, 10, 24, 12), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	if err := e.PrintRegion(image.Rect(8, 10, 24, 12)); err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{
+		0x3c, 0x80,
+		0x44, 0x01, 0x02,
+		0x45, 0x0a, 0x00, 0x0b, 0x00,
+		0x4e, 0x01,
+		0x4f, 0x0a, 0x00,
+		0x24, 0x00, 0x00, 0x00, 0x00,
+		0x22, 0xff,
+		0x20,
+	}
+	errorMsg := validateByteSlice(debug.Bytes(), expected, "PrintRegion function")
+	if len(errorMsg) > 0 {
+		t.Fatal(errorMsg)
+	}
+	if n := countBlack(em.Image(), em.Image().Bounds()); n != 32 {
+		t.Fatalf("Expected 32 black pixels on screen, found %d", n)
+	}
+	if full, partial := em.Refreshes(); full != 1 || partial != 1 {
+		t.Fatalf("Expected 1 full and 1 partial refreshes, found %d and %d", full, partial)
+	}
+
+	if err := e.Sleep(); err != nil {
+		t.Fatal(err)
+	}
+	if !em.Sleeping() {
+		t.Fatal("Expected the panel to be sleeping")
+	}
+}
+
+func TestUnsupportedRefreshMode(t *testing.T) {
+	// Create a dummy "epaper"
+	// (to create a real one, use the example source code, this won't work!)
+	debug := new(bytes.Buffer)
+	e, err := epaper.NewCustom("", "", "", "", ModelSim, true, debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []epaper.RefreshMode{epaper.ModeFast, epaper.ModePartial + 10} {
+		if err := e.SetRefreshMode(m); err != epaper.ErrUnsupported {
+			t.Fatalf("Expected ErrUnsupported, found %v", err)
+		}
+	}
+	if err := e.SetRefreshMode(epaper.ModeFull); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSmallPanels(t *testing.T) {
+	tests := []struct {
+		name  string
+		model epaper.Model
+		init  []byte // Driver output control, then RAM window
+	}{
+		{"1.54 V2", epaper.Model1in54V2, []byte{0x01, 0xc7, 0x00, 0x00, 0x44, 0x00, 0x18, 0x45, 0x00, 0x00, 0xc7, 0x00}},
+		{"2.13 V3", epaper.Model2in13V3, []byte{0x01, 0xf9, 0x00, 0x00, 0x44, 0x00, 0x0f, 0x45, 0x00, 0x00, 0xf9, 0x00}},
+		{"2.13 V4", epaper.Model2in13V4, []byte{0x01, 0xf9, 0x00, 0x00, 0x44, 0x00, 0x0f, 0x45, 0x00, 0x00, 0xf9, 0x00}},
+		{"2.9 V2", epaper.Model2in9V2, []byte{0x01, 0x27, 0x01, 0x00, 0x44, 0x00, 0x0f, 0x45, 0x00, 0x00, 0x27, 0x01}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e, debug := newDriverTestEPaper(t, test.model, gpio.Low)
+			out := debug.Bytes()
+			if !bytes.HasPrefix(out[1:], test.init[:4]) || !bytes.Contains(out, test.init[4:]) {
+				t.Fatalf("Expected the init sequence to set the size of the panel, found %v", out)
+			}
+			if err := e.ClearScreen(); err != nil {
+				t.Fatal(err)
+			}
+
+			// The last column is in the padded byte when the width is not a multiple of 8.
+			w, h := test.model.Width, test.model.Height
+			draw.Draw(e.Display, image.Rect(w-1, 0, w, h), image.NewUniform(color.Black), image.Point{}, draw.Src)
+			if err := e.PrintDisplay(); err != nil {
+				t.Fatal(err)
+			}
+			em := e.Emulator()
+			if n := countBlack(em.Image(), image.Rect(w-1, 0, w, h)); n != h {
+				t.Fatalf("Expected the last column to be black (%d pixels), found %d", h, n)
+			}
+
+			// Partial refresh of the last column.
+			draw.Draw(e.Display, image.Rect(w-1, 0, w, h), image.NewUniform(color.White), image.Point{}, draw.Src)
+			if err := e.PrintRegion(image.Rect(w-1, 0, w, h)); err != nil {
+				t.Fatal(err)
+			}
+			if n := countBlack(em.Image(), em.Image().Bounds()); n != 0 {
+				t.Fatalf("Expected a white screen, found %d black pixels", n)
+			}
+			if full, partial := em.Refreshes(); full != 2 || partial != 1 {
+				t.Fatalf("Expected 2 full and 1 partial refreshes, found %d and %d", full, partial)
+			}
+		})
+	}
+}
+
+func TestPartialMode(t *testing.T) {
+	e, _ := newSSD1680TestEPaper(t)
+	em := e.Emulator()
+	if err := e.SetRefreshMode(epaper.ModePartial); err != nil {
+		t.Fatal(err)
+	}
+
+	// The changed region is refreshed, however large it is.
+	for _, r := range []image.Rectangle{image.Rect(0, 0, 16, 4), image.Rect(0, 0, 176, 264)} {
+		draw.Draw(e.Display, r, image.NewUniform(color.Black), image.Point{}, draw.Src)
+		if err := e.PrintDisplay(); err != nil {
+			t.Fatal(err)
+		}
+		if n := countBlack(em.Image(), em.Image().Bounds()); n != r.Dx()*r.Dy() {
+			t.Fatalf("Expected %d black pixels on screen, found %d", r.Dx()*r.Dy(), n)
+		}
+	}
+	if full, partial := em.Refreshes(); full != 1 || partial != 2 {
+		t.Fatalf("Expected 2 partial refreshes after the first full one, found %d full and %d partial", full, partial)
+	}
+
+	// Nothing changed: nothing is refreshed.
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+	if full, partial := em.Refreshes(); full != 1 || partial != 2 {
+		t.Fatalf("Expected no refresh, found %d full and %d partial", full, partial)
+	}
+}
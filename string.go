@@ -32,9 +32,12 @@ func (e *EPaper) WriteRotate(text string, fontSize float64, fontFile string, rot
 	}
 
 	// Convert the text to image.
-	width := e.model.Width
+	e.displayMu.Lock()
+	bounds := e.Display.Bounds()
+	e.displayMu.Unlock()
+	width := bounds.Dx()
 	if rotate {
-		width = e.model.Height
+		width = bounds.Dy()
 	}
 	pic := text2pic.NewTextPicture(text2pic.Configure{Width: width, BgColor: text2pic.ColorWhite})
 	pic.AddTextLine(text, fontSize, f, text2pic.ColorBlack, text2pic.Padding{Left: 0, Top: 0, Bottom: 0})
@@ -5,14 +5,15 @@ import (
 	"testing"
 
 	"github.com/mcules/go-epaper-lib"
+	"github.com/mcules/go-epaper-lib/epapertest"
 	"periph.io/x/periph/conn/gpio"
 )
 
 func TestWrite(t *testing.T) {
 	expectedDisplayResult := []byte{
-		0x13, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe, 0xff, 0xfc, 0xff, 0xf0,
-		0xff, 0xe1, 0xff, 0xe7, 0xff, 0xc7, 0xff, 0x8f, 0xff, 0x9f, 0xff, 0x1f, 0xff, 0x1f, 0xff, 0xbf, 0xff, 0xfe,
-		0xff, 0xfe, 0xff, 0xfe, 0xff, 0x12,
+		0x13, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe, 0x3f, 0xfc, 0x3f, 0xf0,
+		0x3f, 0xe1, 0x3f, 0xe7, 0x3f, 0xc7, 0x3f, 0x8f, 0x3f, 0x9f, 0x3f, 0x1f, 0x3f, 0x1f, 0x3f, 0xbf, 0x3f, 0xfe,
+		0x3f, 0xfe, 0x3f, 0xfe, 0x3f, 0x12,
 	}
 
 	// Create a dummy "epaper"
@@ -77,12 +78,15 @@ func TestWrite(t *testing.T) {
 		t.Fatal(errorMsg)
 	}
 	debug.Reset()
+
+	// Validating what the panel shows.
+	epapertest.AssertGolden(t, e.Snapshot(), "testdata/write.png")
 }
 
 func TestWriteRotate(t *testing.T) {
 	expectedDisplayResult := []byte{
-		0x13, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xe3, 0xff, 0xc3, 0xff, 0x93, 0xff, 0xb2, 0xff, 0x72, 0xff, 0xf2,
-		0xff, 0xe2, 0xff, 0xe2, 0xff, 0x80, 0xff, 0xc6, 0xff, 0xe6, 0xff, 0xe7, 0xff, 0xe7, 0xff, 0xef, 0xff, 0xff,
+		0x13, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xe3, 0xff, 0xc3, 0x3f, 0x93, 0x3f, 0xb2, 0x7f, 0x72, 0x7f, 0xf2,
+		0x7f, 0xe2, 0x7f, 0xe2, 0x7f, 0x80, 0x7f, 0xc6, 0x7f, 0xe6, 0x7f, 0xe7, 0x3f, 0xe7, 0x3f, 0xef, 0xff, 0xff,
 		0xff, 0xff, 0xff, 0xff, 0xff, 0x12,
 	}
 
@@ -148,4 +152,7 @@ func TestWriteRotate(t *testing.T) {
 		t.Fatal(errorMsg)
 	}
 	debug.Reset()
+
+	// Validating what the panel shows.
+	epapertest.AssertGolden(t, e.Snapshot(), "testdata/write_rotate.png")
 }
\ No newline at end of file
@@ -0,0 +1,182 @@
+package epaper
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// TemperatureSource selects the sensor read by the controller (see EPaper.Temperature).
+type TemperatureSource int
+
+const (
+	// TemperatureInternal is the sensor of the controller.
+	TemperatureInternal TemperatureSource = iota
+
+	// TemperatureExternal is a sensor connected to the I2C pins of the controller (e.g. LM75).
+	TemperatureExternal
+)
+
+// TemperatureDriver is implemented by the drivers able to read the temperature from the controller.
+type TemperatureDriver interface {
+	Driver
+
+	// Temperature reads the sensor source, in degrees Celsius.
+	Temperature(ctx context.Context, source TemperatureSource) (float64, error)
+}
+
+// TemperatureBand is a range of temperatures using a specific waveform (see EPaper.SetTemperatureBands).
+type TemperatureBand struct {
+	Below    float64   // The band covers the temperatures below this one, in degrees Celsius, and above the previous band
+	Waveform *Waveform // Waveform of the full refreshes in the band (nil: the built-in one)
+}
+
+// Temperature returns the temperature around the panel, in degrees Celsius: the result of ReadTemperature if set,
+// otherwise the value read from the TemperatureSource by the controller. Reading the controller requires its data line
+// to be wired to the SPI data line, with spi.HalfDuplex in Config.SPIMode (on the stock HATs, it is not connected and
+// would read zeros): it returns ErrUnsupported otherwise, or if the driver cannot read the sensor.
+func (e *EPaper) Temperature() (float64, error) {
+	return e.TemperatureContext(context.Background())
+}
+
+// TemperatureContext is like Temperature, but it gives up when ctx is done.
+func (e *EPaper) TemperatureContext(ctx context.Context) (float64, error) {
+	e.mu.Lock()
+	defer e.unlock()
+	return e.temperature(ctx)
+}
+
+func (e *EPaper) temperature(ctx context.Context) (float64, error) {
+	if e.ReadTemperature != nil {
+		return e.ReadTemperature()
+	}
+	d, ok := e.driver.(TemperatureDriver)
+	if !ok || !e.halfDuplex {
+		return 0, ErrUnsupported
+	}
+	if !e.initialized {
+		return 0, ErrNotInitialized
+	}
+	return d.Temperature(ctx, e.TemperatureSource)
+}
+
+// SetTemperatureBands makes the full refreshes use the waveform of the band of the current temperature (see
+// Temperature): the first band whose bound is above it, or the built-in waveform above the last band. The bands must be
+// sorted by increasing bounds. They are copied, replace the waveform set by SetWaveform, and are removed when bands is
+// empty.
+//
+// The temperature is read before each full refresh. If it cannot be read, the waveform of the last refresh is kept.
+// It returns ErrUnsupported if the driver cannot use custom waveforms, or if the temperature can be read neither by
+// the controller (see Temperature) nor by ReadTemperature.
+func (e *EPaper) SetTemperatureBands(bands []TemperatureBand) error {
+	e.mu.Lock()
+	defer e.unlock()
+
+	d, ok := e.driver.(WaveformDriver)
+	if !ok {
+		return ErrUnsupported
+	}
+	if _, ok := e.driver.(TemperatureDriver); (!ok || !e.halfDuplex) && e.ReadTemperature == nil && len(bands) > 0 {
+		return ErrUnsupported
+	}
+	for i, band := range bands {
+		if i > 0 && band.Below <= bands[i-1].Below {
+			return fmt.Errorf("%w: the temperature bands are not sorted", ErrInvalidConfig)
+		}
+		if band.Waveform != nil {
+			if err := band.Waveform.Validate(d.Capabilities().Controller); err != nil {
+				return err
+			}
+		}
+	}
+
+	e.bands = append([]TemperatureBand(nil), bands...)
+	for i, band := range e.bands {
+		if band.Waveform != nil {
+			copied := band.Waveform.clone()
+			e.bands[i].Waveform = &copied
+		}
+	}
+	e.band = -1
+	if len(bands) == 0 {
+		return d.SetWaveform(nil)
+	}
+	return nil
+}
+
+// selectWaveform loads the waveform of the temperature band of the current temperature, if the bands are set.
+func (e *EPaper) selectWaveform(ctx context.Context) error {
+	if len(e.bands) == 0 {
+		return nil
+	}
+	t, err := e.temperature(ctx)
+	if err != nil {
+		e.logf("epaper: keeping the waveform, the temperature is unknown: %v", err)
+		return nil
+	}
+
+	band := len(e.bands)
+	for i := range e.bands {
+		if t < e.bands[i].Below {
+			band = i
+			break
+		}
+	}
+	if band == e.band {
+		return nil
+	}
+
+	var w *Waveform
+	if band < len(e.bands) {
+		w = e.bands[band].Waveform
+	}
+	e.logf("epaper: %.1f°C, using the waveform of the temperature band %d", t, band)
+	if err := e.driver.(WaveformDriver).SetWaveform(w); err != nil {
+		return err
+	}
+	e.band = band
+	return nil
+}
+
+// ucTemperature reads a temperature sensor through a controller of the UC81xx family.
+func ucTemperature(ctx context.Context, b Bus, source TemperatureSource) (float64, error) {
+	cmd, tse := CmdTemperatureSensor, byte(0x00)
+	if source == TemperatureExternal {
+		cmd, tse = CmdTemperatureRead, 0x80
+	}
+	if err := b.Send(CmdTemperatureCalibration, []byte{tse}); err != nil {
+		return 0, err
+	}
+	if err := b.WaitUntilIdle(ctx); err != nil {
+		return 0, err
+	}
+	data, err := b.Read(cmd, 2)
+	if err != nil {
+		return 0, err
+	}
+	return decodeTemperature(data)
+}
+
+// Range of temperatures the sensors can measure, in degrees Celsius.
+const (
+	minTemperature = -40
+	maxTemperature = 85
+)
+
+// decodeTemperature decodes a temperature in the format of the sensors: 11 bits in two's complement, left-aligned in 2
+// bytes, in eighths of degree Celsius. A data line which cannot be read gives 0xFFFF (or a value out of the range of
+// the sensors), reported as ErrInvalidTemperature.
+func decodeTemperature(data []byte) (float64, error) {
+	raw := uint16(data[0])<<8 | uint16(data[1])
+	celsius := float64(int16(raw)>>5) / 8
+	if raw == 0xFFFF || celsius < minTemperature || celsius > maxTemperature {
+		return 0, fmt.Errorf("%w: 0x%04x", ErrInvalidTemperature, raw)
+	}
+	return celsius, nil
+}
+
+// encodeTemperature is the inverse of decodeTemperature.
+func encodeTemperature(celsius float64) []byte {
+	v := uint16(int16(math.Round(celsius*8)) << 5)
+	return []byte{byte(v >> 8), byte(v)}
+}
@@ -0,0 +1,203 @@
+package epaper_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/mcules/go-epaper-lib"
+	"periph.io/x/periph/conn/gpio"
+	"periph.io/x/periph/conn/gpio/gpiotest"
+	"periph.io/x/periph/conn/physic"
+	"periph.io/x/periph/conn/spi"
+	"periph.io/x/periph/conn/spi/spitest"
+)
+
+func TestTemperature(t *testing.T) {
+	e, debug := newReadableTestEPaper(t, epaper.Model2in7bw)
+	e.Emulator().SetTemperature(-3.5)
+
+	for _, test := range []struct {
+		source   epaper.TemperatureSource
+		commands []byte
+	}{
+		{epaper.TemperatureInternal, []byte{0x41, 0x00, 0x40}},
+		{epaper.TemperatureExternal, []byte{0x41, 0x80, 0x43}},
+	} {
+		e.TemperatureSource = test.source
+		debug.Reset()
+		celsius, err := e.Temperature()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if celsius != -3.5 {
+			t.Fatalf("Expected -3.5°C, found %v", celsius)
+		}
+		if !bytes.Equal(debug.Bytes(), test.commands) {
+			t.Fatalf("Expected the commands %v, found %v", test.commands, debug.Bytes())
+		}
+	}
+
+	// A sensor not connected to the controller.
+	e.ReadTemperature = func() (float64, error) { return 12.25, nil }
+	if celsius, err := e.Temperature(); err != nil || celsius != 12.25 {
+		t.Fatalf("Expected 12.25°C, found %v (%v)", celsius, err)
+	}
+
+	e, _ = newReadableTestEPaper(t, epaper.Model7in5)
+	if _, err := e.Temperature(); err != epaper.ErrUnsupported {
+		t.Fatalf("Expected ErrUnsupported, found %v", err)
+	}
+}
+
+func TestTemperatureBands(t *testing.T) {
+	e, debug := newReadableTestEPaper(t, epaper.Model4in2)
+	em := e.Emulator()
+
+	cold := epaper.Waveform4in2()
+	cold.WW = append([]byte{0x40, 0x30, 0x30, 0, 0, 0x04}, make([]byte, 36)...)
+	if err := e.SetTemperatureBands([]epaper.TemperatureBand{{Below: 5, Waveform: &cold}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		celsius float64
+		ww      []byte // LUT loaded before the refresh (nil if none)
+	}{
+		{2, cold.WW},
+		{-10, nil},
+		{18, epaper.Waveform4in2().WW},
+		{22, nil},
+	} {
+		em.SetTemperature(test.celsius)
+		draw.Draw(e.Display, image.Rect(0, 0, 8, 8), image.NewUniform(color.Gray{Y: uint8(i % 2 * 0xff)}), image.Point{}, draw.Src)
+		debug.Reset()
+		if err := e.PrintDisplay(); err != nil {
+			t.Fatal(err)
+		}
+		loaded := bytes.Contains(debug.Bytes(), []byte{0x21, 0x40})
+		if test.ww == nil && loaded {
+			t.Fatalf("%v°C: expected the waveform to be kept", test.celsius)
+		}
+		if test.ww != nil && !bytes.Contains(debug.Bytes(), append([]byte{0x21}, test.ww...)) {
+			t.Fatalf("%v°C: expected the waveform of the band to be loaded", test.celsius)
+		}
+	}
+
+	unsorted := []epaper.TemperatureBand{{Below: 5, Waveform: &cold}, {Below: 0}}
+	if err := e.SetTemperatureBands(unsorted); !errors.Is(err, epaper.ErrInvalidConfig) {
+		t.Fatalf("Expected ErrInvalidConfig, found %v", err)
+	}
+
+	// The SSD16xx controllers cannot read their sensor: the temperature must come from ReadTemperature.
+	e, _ = newDriverTestEPaper(t, epaper.Model2in7bwV2, gpio.Low)
+	bands := []epaper.TemperatureBand{{Below: 5}}
+	if err := e.SetTemperatureBands(bands); err != epaper.ErrUnsupported {
+		t.Fatalf("Expected ErrUnsupported, found %v", err)
+	}
+	e.ReadTemperature = func() (float64, error) { return 20, nil }
+	if err := e.SetTemperatureBands(bands); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newReadableTestEPaper creates a dummy, initialized, "epaper" of the given model whose data line can be read.
+func newReadableTestEPaper(t *testing.T, model epaper.Model) (*epaper.EPaper, *bytes.Buffer) {
+	debug := new(bytes.Buffer)
+	e, err := epaper.Open(model, epaper.WithSimulation(debug), epaper.WithSPIMode(spi.Mode0|spi.HalfDuplex))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Forcing the BUSY to High to avoid being blocked because of WaitUntilIdle().
+	// Do not do this on real cases!
+	e.Busy.Out(gpio.High)
+
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return e, debug
+}
+
+// constantPort is a SPI port whose data line always reads the same value.
+type constantPort struct {
+	*spitest.RecordRaw
+	value byte
+}
+
+func (p *constantPort) Connect(f physic.Frequency, mode spi.Mode, bits int) (spi.Conn, error) {
+	c, err := p.RecordRaw.Connect(f, mode, bits)
+	return &constantConn{c, p.value}, err
+}
+
+type constantConn struct {
+	spi.Conn
+	value byte
+}
+
+func (c *constantConn) Tx(w, r []byte) error {
+	for i := range r {
+		r[i] = c.value
+	}
+	return c.Conn.Tx(w, nil)
+}
+
+// newConstantTestEPaper creates an initialized 4.2 inches "epaper" whose data line reads value.
+func newConstantTestEPaper(t *testing.T, value byte, mode spi.Mode) *epaper.EPaper {
+	busy := &gpiotest.Pin{N: "BUSY"}
+	pins := []gpio.PinIO{&gpiotest.Pin{N: "DC"}, &gpiotest.Pin{N: "CS"}, &gpiotest.Pin{N: "RST"}, busy}
+	port := &constantPort{spitest.NewRecordRaw(new(bytes.Buffer)), value}
+	e, err := epaper.Open(epaper.Model4in2, epaper.WithPinIO(pins[0], pins[1], pins[2], pins[3]), epaper.WithPort(port),
+		epaper.WithSPIMode(mode))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Forcing the BUSY to High to avoid being blocked because of WaitUntilIdle().
+	// Do not do this on real cases!
+	busy.Out(gpio.High)
+
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestTemperatureNotReadable(t *testing.T) {
+	// A data line floating high.
+	e := newConstantTestEPaper(t, 0xFF, spi.Mode0|spi.HalfDuplex)
+	if _, err := e.Temperature(); !errors.Is(err, epaper.ErrInvalidTemperature) {
+		t.Fatalf("Expected ErrInvalidTemperature, found %v", err)
+	}
+
+	// The waveform is kept when the temperature is unknown.
+	cold := epaper.Waveform4in2()
+	if err := e.SetTemperatureBands([]epaper.TemperatureBand{{Below: 5, Waveform: &cold}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTemperatureNotWired(t *testing.T) {
+	// On the stock HATs, the data line is not connected and reads zeros (0°C): the controller is not read without
+	// spi.HalfDuplex.
+	e := newConstantTestEPaper(t, 0x00, spi.Mode0)
+	if _, err := e.Temperature(); err != epaper.ErrUnsupported {
+		t.Fatalf("Expected ErrUnsupported, found %v", err)
+	}
+	cold := epaper.Waveform4in2()
+	if err := e.SetTemperatureBands([]epaper.TemperatureBand{{Below: 5, Waveform: &cold}}); err != epaper.ErrUnsupported {
+		t.Fatalf("Expected ErrUnsupported, found %v", err)
+	}
+
+	// ReadTemperature is used instead.
+	e.ReadTemperature = func() (float64, error) { return 20, nil }
+	if celsius, err := e.Temperature(); err != nil || celsius != 20 {
+		t.Fatalf("Expected 20°C, found %v (%v)", celsius, err)
+	}
+}
@@ -0,0 +1,99 @@
+package epaper_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/mcules/go-epaper-lib"
+	"periph.io/x/periph/conn/gpio"
+)
+
+// countColor counts the pixels of img in r with the color c.
+func countColor(img image.Image, r image.Rectangle, c color.Color) int {
+	n := 0
+	r0, g0, b0, _ := c.RGBA()
+	for j := r.Min.Y; j < r.Max.Y; j++ {
+		for i := r.Min.X; i < r.Max.X; i++ {
+			r1, g1, b1, _ := img.At(i, j).RGBA()
+			if r0 == r1 && g0 == g1 && b0 == b1 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestTriColorPanels(t *testing.T) {
+	tests := []struct {
+		name  string
+		model epaper.Model
+		idle  gpio.Level
+	}{
+		{"2.7 B V2", epaper.Model2in7bV2, gpio.Low},
+		{"4.2 B", epaper.Model4in2b, gpio.High},
+		{"4.2 C", epaper.Model4in2c, gpio.High},
+		{"7.5 B V2", epaper.Model7in5bV2, gpio.High},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e, _ := newDriverTestEPaper(t, test.model, test.idle)
+			if err := e.ClearScreen(); err != nil {
+				t.Fatal(err)
+			}
+			em := e.Emulator()
+			screen := em.Image().Bounds()
+			if n := countColor(em.Image(), screen, color.White); n != screen.Dx()*screen.Dy() {
+				t.Fatalf("Expected a white screen after clearing it, found %d white pixels", n)
+			}
+
+			black, colored := image.Rect(0, 0, 16, 10), image.Rect(16, 0, 32, 10)
+			draw.Draw(e.Display, black, image.NewUniform(color.Black), image.Point{}, draw.Src)
+			draw.Draw(e.Display, colored, image.NewUniform(test.model.Color), image.Point{}, draw.Src)
+			if err := e.PrintDisplay(); err != nil {
+				t.Fatal(err)
+			}
+
+			img := em.Image()
+			if n := countColor(img, black, color.Black); n != 160 {
+				t.Fatalf("Expected 160 black pixels, found %d", n)
+			}
+			if n := countColor(img, colored, test.model.Color); n != 160 {
+				t.Fatalf("Expected 160 pixels of the third color, found %d", n)
+			}
+			if n := countColor(img, screen, color.White); n != screen.Dx()*screen.Dy()-320 {
+				t.Fatalf("Expected the rest of the screen to be white, found %d white pixels", n)
+			}
+
+			// Partial refreshes need frames of 1 bit per pixel.
+			if err := e.PrintRegion(black); err != epaper.ErrUnsupported {
+				t.Fatalf("Expected ErrUnsupported, found %v", err)
+			}
+		})
+	}
+}
+
+func TestChromaClassifier(t *testing.T) {
+	e, _ := newDriverTestEPaper(t, epaper.Model4in2b, gpio.High)
+	e.Classifier = epaper.ChromaClassifier(0x60, 0x80)
+
+	// Without the classifier, dark red is closer to black and orange to white.
+	darkRed, orange := color.RGBA{R: 0x70, A: 0xff}, color.RGBA{R: 0xff, G: 0xa0, B: 0x60, A: 0xff}
+	gray := color.RGBA{R: 0x70, G: 0x70, B: 0x70, A: 0xff}
+	draw.Draw(e.Display, image.Rect(0, 0, 8, 8), image.NewUniform(darkRed), image.Point{}, draw.Src)
+	draw.Draw(e.Display, image.Rect(8, 0, 16, 8), image.NewUniform(orange), image.Point{}, draw.Src)
+	draw.Draw(e.Display, image.Rect(16, 0, 24, 8), image.NewUniform(gray), image.Point{}, draw.Src)
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+
+	img := e.Emulator().Image()
+	if n := countColor(img, image.Rect(0, 0, 16, 8), epaper.Model4in2b.Color); n != 128 {
+		t.Fatalf("Expected the saturated colors to be red, found %d red pixels", n)
+	}
+	if n := countColor(img, image.Rect(16, 0, 24, 8), color.Black); n != 64 {
+		t.Fatalf("Expected the dark gray to be black, found %d black pixels", n)
+	}
+}
@@ -0,0 +1,88 @@
+package epaper
+
+import (
+	"bytes"
+	"context"
+	"image/color"
+	"time"
+)
+
+// uc8179 drives the black-and-white panels with an UC8179 controller (7.5 inches V2, 5.83 inches V2), using its
+// built-in waveforms. The controller takes the frames inverted (a bit is set for a black pixel).
+type uc8179 struct {
+	bus   Bus
+	model Model
+}
+
+// newUc8179 creates the driver for a black-and-white panel with an UC8179 controller.
+func newUc8179(b Bus, m Model) Driver {
+	return &uc8179{bus: b, model: m}
+}
+
+func (d *uc8179) Capabilities() Capabilities {
+	return Capabilities{
+		Palette: color.Palette{color.Black, color.White},
+	}
+}
+
+func (d *uc8179) Init(ctx context.Context) error {
+	if err := d.bus.Reset(); err != nil {
+		return err
+	}
+
+	err := sendSequence(d.bus, []command{
+		{CmdPowerSetting, []byte{0x07, 0x07, 0x3f, 0x3f}},
+		{CmdPowerOn, nil},
+	})
+	if err != nil {
+		return err
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err := d.bus.WaitUntilIdle(ctx); err != nil {
+		return err
+	}
+
+	w, h := d.model.Width, d.model.Height
+	return sendSequence(d.bus, []command{
+		{CmdPanelSetting, []byte{0x1f}},
+		{CmdTconResolution, []byte{byte(w >> 8), byte(w), byte(h >> 8), byte(h)}},
+		{CmdPartialDataStartTransimission2, []byte{0x00}}, // Dual SPI off
+		{CmdVcomDataIntervalSet, []byte{0x10, 0x07}},
+		{CmdTconSetting, []byte{0x22}},
+	})
+}
+
+func (d *uc8179) Clear(ctx context.Context) error {
+	size := (d.model.Width + 7) / 8 * d.model.Height
+	if err := d.bus.Send(CmdDataStartTransimission1, bytes.Repeat([]byte{0xFF}, size)); err != nil {
+		return err
+	}
+	return d.bus.Send(CmdDataStartTransimission2, make([]byte, size))
+}
+
+func (d *uc8179) WriteFrame(ctx context.Context, frame []byte) error {
+	inverted := make([]byte, len(frame))
+	for i, b := range frame {
+		inverted[i] = ^b
+	}
+	return d.bus.Send(CmdDataStartTransimission2, inverted)
+}
+
+func (d *uc8179) Refresh(ctx context.Context) error {
+	if err := d.bus.Send(CmdDisplayRefresh, nil); err != nil {
+		return err
+	}
+	time.Sleep(100 * time.Millisecond)
+	return d.bus.WaitUntilIdle(ctx)
+}
+
+func (d *uc8179) Sleep(ctx context.Context) error {
+	if err := d.bus.Send(CmdPowerOff, nil); err != nil {
+		return err
+	}
+	if err := d.bus.WaitUntilIdle(ctx); err != nil {
+		return err
+	}
+	return d.bus.Send(CMdDeepSleep, []byte{0xA5})
+}
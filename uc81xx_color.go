@@ -0,0 +1,117 @@
+package epaper
+
+import (
+	"bytes"
+	"context"
+	"image/color"
+	"time"
+)
+
+// uc81xxColor drives the tri-color panels with a controller of the UC81xx family, using its built-in waveforms. The
+// black plane is sent with DTM1 and the plane of the third color with DTM2.
+type uc81xxColor struct {
+	bus         Bus
+	model       Model
+	powerUp     []command // Sent before POWER ON
+	setup       []command // Sent once powered on
+	invertColor bool      // A bit of the color plane is set for a pixel of the third color
+}
+
+// newEpd4in2b creates the driver for the tri-color 4.2 inches panels (UC8176 controller).
+func newEpd4in2b(b Bus, m Model) Driver {
+	return &uc81xxColor{
+		bus:   b,
+		model: m,
+		powerUp: []command{
+			{CmdBoosterSoftStart, []byte{0x17, 0x17, 0x17}},
+		},
+		setup: []command{
+			{CmdPanelSetting, []byte{0x0f}}, // Black-white-red, LUTs from the OTP
+		},
+	}
+}
+
+// newEpd7in5bV2 creates the driver for the V2 revision of the tri-color 7.5 inches panel (UC8179 controller).
+func newEpd7in5bV2(b Bus, m Model) Driver {
+	w, h := m.Width, m.Height
+	return &uc81xxColor{
+		bus:   b,
+		model: m,
+		powerUp: []command{
+			{CmdPowerSetting, []byte{0x07, 0x07, 0x3f, 0x3f}},
+		},
+		setup: []command{
+			{CmdPanelSetting, []byte{0x0f}}, // Black-white-red, LUTs from the OTP
+			{CmdTconResolution, []byte{byte(w >> 8), byte(w), byte(h >> 8), byte(h)}},
+			{CmdPartialDataStartTransimission2, []byte{0x00}}, // Dual SPI off
+			{CmdVcomDataIntervalSet, []byte{0x11, 0x07}},
+			{CmdTconSetting, []byte{0x22}},
+		},
+		invertColor: true,
+	}
+}
+
+func (d *uc81xxColor) Capabilities() Capabilities {
+	return Capabilities{
+		Palette: color.Palette{color.Black, color.White, d.model.Color},
+		Format:  FormatPlanes,
+	}
+}
+
+func (d *uc81xxColor) Init(ctx context.Context) error {
+	if err := d.bus.Reset(); err != nil {
+		return err
+	}
+	if err := sendSequence(d.bus, append(d.powerUp, command{CmdPowerOn, nil})); err != nil {
+		return err
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err := d.bus.WaitUntilIdle(ctx); err != nil {
+		return err
+	}
+	return sendSequence(d.bus, d.setup)
+}
+
+func (d *uc81xxColor) Clear(ctx context.Context) error {
+	white := bytes.Repeat([]byte{0xFF}, (d.model.Width+7)/8*d.model.Height)
+	return d.writePlanes(white, white)
+}
+
+func (d *uc81xxColor) WriteFrame(ctx context.Context, frame []byte) error {
+	n := len(frame) / 2
+	return d.writePlanes(frame[:n], frame[n:])
+}
+
+// writePlanes sends the black plane and the color plane (as converted).
+func (d *uc81xxColor) writePlanes(black, colored []byte) error {
+	if d.invertColor {
+		inverted := make([]byte, len(colored))
+		for i, b := range colored {
+			inverted[i] = ^b
+		}
+		colored = inverted
+	}
+	if err := d.bus.Send(CmdDataStartTransimission1, black); err != nil {
+		return err
+	}
+	return d.bus.Send(CmdDataStartTransimission2, colored)
+}
+
+func (d *uc81xxColor) Refresh(ctx context.Context) error {
+	if err := d.bus.Send(CmdDisplayRefresh, nil); err != nil {
+		return err
+	}
+	time.Sleep(100 * time.Millisecond)
+	return d.bus.WaitUntilIdle(ctx)
+}
+
+func (d *uc81xxColor) Sleep(ctx context.Context) error {
+	if err := d.bus.Send(CmdPowerOff, nil); err != nil {
+		return err
+	}
+	if err := d.bus.WaitUntilIdle(ctx); err != nil {
+		return err
+	}
+	return d.bus.Send(CMdDeepSleep, []byte{0xA5})
+}
@@ -0,0 +1,234 @@
+package epaper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Waveform is a set of LUTs (look-up tables) describing the voltages applied to the pixels during a full refresh.
+// Custom waveforms can refresh faster, or keep a good contrast in a cold environment. The LUTs used depend on the
+// controller of the panel (see Validate).
+type Waveform struct {
+	// VCOM, WW, BW, WB and BB are the LUTs of the UC81xx controllers (commands 0x20 to 0x24), for the common electrode
+	// and for the pixels going from white or black (first letter) to white or black. They are made of 7 groups of 6
+	// bytes: the levels of the 4 phases (2 bits each), their numbers of frames and the repeat count of the group. VCOM
+	// has 2 more bytes.
+	VCOM []byte `json:"vcom,omitempty"`
+	WW   []byte `json:"ww,omitempty"`
+	BW   []byte `json:"bw,omitempty"`
+	WB   []byte `json:"wb,omitempty"`
+	BB   []byte `json:"bb,omitempty"`
+
+	// LUT is the LUT of the SSD16xx controllers (command 0x32): the levels of 5 LUTs for 12 groups (60 bytes), then the
+	// numbers of frames of the 4 phases of each group, with their repeat counts (84 bytes), the frame rates (6 bytes)
+	// and the gate scan selection (3 bytes). The LUTs of the Waveshare examples have 6 more bytes, loaded with their
+	// own commands: the end option (0x3F), the gate voltage (0x03), the source voltages (0x04) and VCOM (0x2C).
+	LUT []byte `json:"lut,omitempty"`
+}
+
+// Sizes of the LUTs.
+const (
+	ucLutGroups   = 7
+	ucGroupSize   = 6
+	ucLutSize     = ucLutGroups * ucGroupSize
+	ucVcomLutSize = ucLutSize + 2
+	ssdLutGroups  = 12
+	ssdLutSize    = 153
+	ssdVoltsSize  = 6 // Voltages following the LUT in the Waveshare examples
+)
+
+// Validate checks that w has the LUTs of the controller c, with their expected lengths, and that the voltages of every
+// phase are applied for some frames. The errors wrap ErrInvalidWaveform.
+func (w *Waveform) Validate(c Controller) error {
+	if c == ControllerSSD16xx {
+		if w.VCOM != nil || w.WW != nil || w.BW != nil || w.WB != nil || w.BB != nil {
+			return fmt.Errorf("%w: the SSD16xx controllers only use LUT", ErrInvalidWaveform)
+		}
+		return w.validateSSD()
+	}
+
+	if w.LUT != nil {
+		return fmt.Errorf("%w: the UC81xx controllers do not use LUT", ErrInvalidWaveform)
+	}
+	if len(w.VCOM) != ucVcomLutSize {
+		return fmt.Errorf("%w: VCOM has %d bytes instead of %d", ErrInvalidWaveform, len(w.VCOM), ucVcomLutSize)
+	}
+	for _, lut := range []struct {
+		name string
+		data []byte
+	}{{"WW", w.WW}, {"BW", w.BW}, {"WB", w.WB}, {"BB", w.BB}} {
+		if len(lut.data) != ucLutSize {
+			return fmt.Errorf("%w: %s has %d bytes instead of %d", ErrInvalidWaveform, lut.name, len(lut.data), ucLutSize)
+		}
+		frames := 0
+		for g := 0; g < ucLutGroups; g++ {
+			group := lut.data[g*ucGroupSize : (g+1)*ucGroupSize]
+			n := 0
+			for _, f := range group[1:5] {
+				n += int(f)
+			}
+			if group[0] != 0 && (n == 0 || group[5] == 0) {
+				return fmt.Errorf("%w: the levels of the group %d of %s are never applied", ErrInvalidWaveform, g, lut.name)
+			}
+			frames += n * int(group[5])
+		}
+		if frames == 0 {
+			return fmt.Errorf("%w: %s is empty", ErrInvalidWaveform, lut.name)
+		}
+	}
+	return nil
+}
+
+func (w *Waveform) validateSSD() error {
+	if len(w.LUT) != ssdLutSize && len(w.LUT) != ssdLutSize+ssdVoltsSize {
+		return fmt.Errorf("%w: LUT has %d bytes instead of %d or %d", ErrInvalidWaveform, len(w.LUT), ssdLutSize,
+			ssdLutSize+ssdVoltsSize)
+	}
+
+	frames := 0
+	for g := 0; g < ssdLutGroups; g++ {
+		timing := w.LUT[5*ssdLutGroups+7*g:]
+		n := int(timing[0]) + int(timing[1]) + int(timing[3]) + int(timing[4])
+		for l := 0; l < 5; l++ {
+			if w.LUT[l*ssdLutGroups+g] != 0 && n == 0 {
+				return fmt.Errorf("%w: the levels of the group %d of LUT %d are never applied", ErrInvalidWaveform, g, l)
+			}
+		}
+		frames += n
+	}
+	if frames == 0 {
+		return fmt.Errorf("%w: LUT is empty", ErrInvalidWaveform)
+	}
+	return nil
+}
+
+// ssdCommands returns the commands loading the LUT of a SSD16xx controller, with its voltages if any.
+func (w *Waveform) ssdCommands() []command {
+	commands := []command{{ssdWriteLUT, w.LUT[:ssdLutSize]}}
+	if volts := w.LUT[ssdLutSize:]; len(volts) == ssdVoltsSize {
+		commands = append(commands,
+			command{ssdEndOption, volts[0:1]},
+			command{ssdGateVoltage, volts[1:2]},
+			command{ssdSourceVoltage, volts[2:5]},
+			command{ssdWriteVCOM, volts[5:6]},
+		)
+	}
+	return commands
+}
+
+// clone returns a copy of w which does not share its LUTs.
+func (w *Waveform) clone() Waveform {
+	return Waveform{
+		VCOM: cloneLut(w.VCOM),
+		WW:   cloneLut(w.WW),
+		BW:   cloneLut(w.BW),
+		WB:   cloneLut(w.WB),
+		BB:   cloneLut(w.BB),
+		LUT:  cloneLut(w.LUT),
+	}
+}
+
+func cloneLut(lut []byte) []byte {
+	return append([]byte(nil), lut...)
+}
+
+// commands returns the commands loading the LUTs of a UC81xx controller.
+func (w *Waveform) commands() []command {
+	return []command{
+		{CmdLutForVcom, w.VCOM},
+		{CmdLutBlue, w.WW},
+		{CmdLutWhite, w.BW},
+		{CmdLutGray1, w.WB},
+		{CmdLutGray2, w.BB},
+	}
+}
+
+// LoadWaveform reads a waveform from a file (see ParseWaveform).
+func LoadWaveform(path string) (*Waveform, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseWaveform(f)
+}
+
+// ParseWaveform reads a waveform in JSON, an object with the fields of Waveform as arrays of numbers, or as C arrays
+// like the ones of the Waveshare examples:
+//
+//	static const unsigned char lut_vcom_dc[] = { 0x00, 0x00, ... };
+//
+// The LUT of each array is told by its name: vcom, ww, bw, wb or bb (e.g. EPD_2in7_lut_ww). A single array with
+// another name is the LUT of the SSD16xx controllers.
+func ParseWaveform(r io.Reader) (*Waveform, error) {
+	text, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Waveform{}
+	if bytes.HasPrefix(bytes.TrimSpace(text), []byte("{")) {
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(w); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidWaveform, err)
+		}
+		return w, nil
+	}
+
+	arrays := cArray.FindAllSubmatch(cComment.ReplaceAll(text, nil), -1)
+	if len(arrays) == 0 {
+		return nil, fmt.Errorf("%w: no array found", ErrInvalidWaveform)
+	}
+	for _, a := range arrays {
+		name, data := string(a[1]), []byte{}
+		for _, v := range strings.Split(string(a[2]), ",") {
+			if v = strings.TrimSpace(v); v == "" {
+				continue
+			}
+			b, err := strconv.ParseUint(v, 0, 8)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInvalidWaveform, name, err)
+			}
+			data = append(data, byte(b))
+		}
+
+		lut := w.lutNamed(name)
+		if *lut != nil {
+			return nil, fmt.Errorf("%w: unexpected array %s", ErrInvalidWaveform, name)
+		}
+		*lut = data
+	}
+	return w, nil
+}
+
+var (
+	cComment = regexp.MustCompile(`(?s)//[^\n]*|/\*.*?\*/`)
+	cArray   = regexp.MustCompile(`(\w+)\s*\[\s*\w*\s*\]\s*=\s*\{([^}]*)\}`)
+)
+
+// lutNamed returns the field of w holding the C array name.
+func (w *Waveform) lutNamed(name string) *[]byte {
+	for _, token := range strings.Split(strings.ToLower(name), "_") {
+		switch {
+		case strings.HasPrefix(token, "vcom"):
+			return &w.VCOM
+		case token == "ww":
+			return &w.WW
+		case token == "bw":
+			return &w.BW
+		case token == "wb":
+			return &w.WB
+		case token == "bb":
+			return &w.BB
+		}
+	}
+	return &w.LUT
+}
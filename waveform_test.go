@@ -0,0 +1,239 @@
+package epaper_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mcules/go-epaper-lib"
+	"periph.io/x/periph/conn/gpio"
+)
+
+// cArray formats data like the LUTs of the Waveshare examples.
+func cArray(name string, data []byte) string {
+	values := make([]string, len(data))
+	for i, b := range data {
+		values[i] = fmt.Sprintf("0x%02X", b)
+	}
+	return fmt.Sprintf("// %s\nstatic const unsigned char %s[] = {\n%s,\n};\n", strings.ToUpper(name), name, strings.Join(values, ", "))
+}
+
+// ssdLut returns a valid LUT for the SSD16xx controllers.
+func ssdLut() []byte {
+	lut := make([]byte, 153)
+	lut[0] = 0x80    // Level of the first phase of the group 0 of the LUT 0
+	lut[60] = 0x0a   // Frames of the first phase of the group 0
+	lut[60+6] = 0x01 // Repeat count of the group 0
+	return lut
+}
+
+// ws2030 is the WS_20_30 LUT of the Waveshare examples of the 2.13 inches V3 panel, with its voltages.
+var ws2030 = []byte{
+	0x80, 0x4A, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x40, 0x4A, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x80, 0x4A, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x40, 0x4A, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x0F, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x0F, 0x00, 0x00, 0x0F, 0x00, 0x00, 0x02,
+	0x0F, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x00, 0x00, 0x00,
+	0x22, 0x17, 0x41, 0x00, 0x32, 0x36,
+}
+
+func TestWaveformValidation(t *testing.T) {
+	partial := epaper.Waveform{
+		VCOM: epaper.Model2in7PartialLutVcomDc,
+		WW:   epaper.Model2in7PartialLutWw,
+		BW:   epaper.Model2in7PartialLutBw,
+		WB:   epaper.Model2in7PartialLutWb,
+		BB:   epaper.Model2in7PartialLutBb,
+	}
+	gray := epaper.Waveform{
+		VCOM: epaper.Model2in7GrayLutVcomDc,
+		WW:   epaper.Model2in7GrayLutWw,
+		BW:   epaper.Model2in7GrayLutBw,
+		WB:   epaper.Model2in7GrayLutWb,
+		BB:   epaper.Model2in7GrayLutBb,
+	}
+	for _, w := range []epaper.Waveform{epaper.Waveform2in7(), epaper.Waveform4in2(), partial, gray} {
+		if err := w.Validate(epaper.ControllerUC81xx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ssd := epaper.Waveform{LUT: ssdLut()}
+	if err := ssd.Validate(epaper.ControllerSSD16xx); err != nil {
+		t.Fatal(err)
+	}
+	waveshare := epaper.Waveform{LUT: ws2030}
+	if err := waveshare.Validate(epaper.ControllerSSD16xx); err != nil {
+		t.Fatal(err)
+	}
+
+	levelsWithoutFrames := append([]byte{0x80, 0, 0, 0, 0, 1}, epaper.Waveform2in7().WW[6:]...)
+	silentSSD := ssdLut()
+	silentSSD[60] = 0
+
+	for name, test := range map[string]struct {
+		w          epaper.Waveform
+		controller epaper.Controller
+	}{
+		"short VCOM":            {epaper.Waveform{VCOM: epaper.Waveform2in7().VCOM[2:], WW: epaper.Waveform2in7().WW, BW: epaper.Waveform2in7().BW, WB: epaper.Waveform2in7().WB, BB: epaper.Waveform2in7().BB}, epaper.ControllerUC81xx},
+		"missing LUT":           {epaper.Waveform{VCOM: epaper.Waveform2in7().VCOM, WW: epaper.Waveform2in7().WW}, epaper.ControllerUC81xx},
+		"levels without frames": {epaper.Waveform{VCOM: epaper.Waveform2in7().VCOM, WW: levelsWithoutFrames, BW: epaper.Waveform2in7().BW, WB: epaper.Waveform2in7().WB, BB: epaper.Waveform2in7().BB}, epaper.ControllerUC81xx},
+		"SSD LUT on UC":         {ssd, epaper.ControllerUC81xx},
+		"UC LUTs on SSD":        {epaper.Waveform2in7(), epaper.ControllerSSD16xx},
+		"short SSD LUT":         {epaper.Waveform{LUT: ssdLut()[:150]}, epaper.ControllerSSD16xx},
+		"short SSD voltages":    {epaper.Waveform{LUT: ws2030[:157]}, epaper.ControllerSSD16xx},
+		"SSD levels":            {epaper.Waveform{LUT: silentSSD}, epaper.ControllerSSD16xx},
+	} {
+		if err := test.w.Validate(test.controller); !errors.Is(err, epaper.ErrInvalidWaveform) {
+			t.Fatalf("%s: expected ErrInvalidWaveform, found %v", name, err)
+		}
+	}
+}
+
+func TestBuiltInWaveforms(t *testing.T) {
+	for _, test := range []struct {
+		model    epaper.Model
+		waveform func() epaper.Waveform
+	}{
+		{epaper.Model2in7bw, epaper.Waveform2in7},
+		{epaper.Model4in2, epaper.Waveform4in2},
+	} {
+		builtIn := test.waveform()
+
+		// Changing a copy of the built-in waveform, or the exported LUTs, does not change the one loaded by Init().
+		edited := test.waveform()
+		edited.WW[1] ^= 0xff
+		copied := edited
+		copied.BW[1] ^= 0xff
+		epaper.Model2in7LutWw[1] ^= 0xff
+		epaper.Model4in2LutWw[1] ^= 0xff
+		_, debug := newDriverTestEPaper(t, test.model, gpio.High)
+		epaper.Model2in7LutWw[1] ^= 0xff
+		epaper.Model4in2LutWw[1] ^= 0xff
+
+		for cmd, lut := range map[byte][]byte{0x21: builtIn.WW, 0x22: builtIn.BW} {
+			if !bytes.Contains(debug.Bytes(), append([]byte{cmd}, lut...)) {
+				t.Fatalf("%dx%d: expected the built-in LUT 0x%02x to be loaded by Init()", test.model.Width,
+					test.model.Height, cmd)
+			}
+		}
+	}
+}
+
+func TestParseWaveform(t *testing.T) {
+	w := epaper.Waveform2in7()
+	text := "/* Fast waveform */\n" + cArray("EPD_2in7_lut_vcom_dc", w.VCOM) + cArray("EPD_2in7_lut_ww", w.WW) +
+		cArray("EPD_2in7_lut_bw", w.BW) + cArray("EPD_2in7_lut_wb", w.WB) + cArray("EPD_2in7_lut_bb", w.BB)
+	parsed, err := epaper.ParseWaveform(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, lut := range map[string][2][]byte{
+		"VCOM": {parsed.VCOM, w.VCOM}, "WW": {parsed.WW, w.WW}, "BW": {parsed.BW, w.BW}, "WB": {parsed.WB, w.WB}, "BB": {parsed.BB, w.BB},
+	} {
+		if !bytes.Equal(lut[0], lut[1]) {
+			t.Fatalf("%s: expected %v, found %v", name, lut[1], lut[0])
+		}
+	}
+
+	// A single array with another name is the LUT of the SSD16xx controllers.
+	parsed, err = epaper.ParseWaveform(strings.NewReader(cArray("WS_20_30", ws2030)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(parsed.LUT, ws2030) {
+		t.Fatalf("Expected the SSD16xx LUT, found %v", parsed.LUT)
+	}
+
+	// JSON, from a file.
+	path := filepath.Join(t.TempDir(), "waveform.json")
+	if err := ioutil.WriteFile(path, []byte(`{"lut": [128, 0, 255]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err = epaper.LoadWaveform(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(parsed.LUT, []byte{128, 0, 255}) {
+		t.Fatalf("Expected the LUT of the JSON file, found %v", parsed.LUT)
+	}
+
+	for _, text := range []string{"", `{"vcom": [256]}`, `{"unknown": []}`, cArray("lut_ww", []byte{1}) + cArray("lut_ww", []byte{2})} {
+		if _, err := epaper.ParseWaveform(strings.NewReader(text)); !errors.Is(err, epaper.ErrInvalidWaveform) {
+			t.Fatalf("%q: expected ErrInvalidWaveform, found %v", text, err)
+		}
+	}
+}
+
+func TestSetWaveform(t *testing.T) {
+	e, debug := newDriverTestEPaper(t, epaper.Model2in7bw, gpio.High)
+
+	custom := epaper.Waveform2in7()
+	custom.WW = append([]byte{0x40, 0x05, 0, 0, 0, 0x01}, make([]byte, 36)...)
+	if err := e.SetWaveform(&custom); err != nil {
+		t.Fatal(err)
+	}
+	debug.Reset()
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(debug.Bytes(), append([]byte{0x21}, custom.WW...)) {
+		t.Fatal("Expected the custom LUT to be loaded before the refresh")
+	}
+
+	// Invalid waveforms are rejected.
+	custom.WW = custom.WW[:6]
+	if err := e.SetWaveform(&custom); !errors.Is(err, epaper.ErrInvalidWaveform) {
+		t.Fatalf("Expected ErrInvalidWaveform, found %v", err)
+	}
+
+	// The SSD16xx controllers load the LUT before each full refresh.
+	e, debug = newDriverTestEPaper(t, epaper.Model2in7bwV2, gpio.Low)
+	if err := e.SetWaveform(&epaper.Waveform{LUT: ssdLut()}); err != nil {
+		t.Fatal(err)
+	}
+	debug.Reset()
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(debug.Bytes(), append(append([]byte{0x32}, ssdLut()...), 0x22, 0xc7, 0x20)) {
+		t.Fatal("Expected the custom LUT to be loaded before the refresh")
+	}
+
+	// The voltages of the LUTs of the Waveshare examples are loaded after them.
+	if err := e.SetWaveform(&epaper.Waveform{LUT: ws2030}); err != nil {
+		t.Fatal(err)
+	}
+	debug.Reset()
+	if err := e.PrintDisplay(); err != nil {
+		t.Fatal(err)
+	}
+	expected := append(append([]byte{0x32}, ws2030[:153]...), 0x3f, 0x22, 0x03, 0x17, 0x04, 0x41, 0x00, 0x32, 0x2c, 0x36,
+		0x22, 0xc7, 0x20)
+	if !bytes.HasSuffix(debug.Bytes(), expected) {
+		t.Fatal("Expected the Waveshare LUT and its voltages to be loaded before the refresh")
+	}
+
+	// Panels using the waveforms of their OTP memory.
+	e, _ = newDriverTestEPaper(t, epaper.Model7in5V2, gpio.High)
+	w := epaper.Waveform2in7()
+	if err := e.SetWaveform(&w); err != epaper.ErrUnsupported {
+		t.Fatalf("Expected ErrUnsupported, found %v", err)
+	}
+}
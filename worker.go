@@ -0,0 +1,120 @@
+package epaper
+
+import (
+	"context"
+	"image"
+	"image/draw"
+	"sync"
+)
+
+// Frame is an image sent to a Worker to be printed.
+type Frame struct {
+	// Image is drawn on EPaper.Display, at the same coordinates, before printing it.
+	Image image.Image
+
+	// Done receives the result of the frame, unless it is nil: nil once it is on screen, ErrSuperseded when a newer
+	// frame replaced it before it was printed, or the error of the refresh. The worker never blocks on it, so it must
+	// have room for the result (e.g. make(chan error, 1)).
+	Done chan<- error
+}
+
+// Worker prints the frames sent to it in the background, one at a time. The frames received while a refresh is in
+// progress are coalesced: only the newest one is printed next.
+type Worker struct {
+	e       *EPaper
+	frames  chan Frame
+	stop    sync.Once
+	stopped chan struct{}
+}
+
+// StartWorker starts printing the frames sent to the returned Worker, until ctx is done (the refresh in progress is
+// then canceled, and the frames sent afterwards fail with the error of ctx) or Worker.Stop() is called.
+func (e *EPaper) StartWorker(ctx context.Context) *Worker {
+	w := &Worker{e: e, frames: make(chan Frame), stopped: make(chan struct{})}
+	go w.run(ctx)
+	return w
+}
+
+// Frames returns the channel receiving the frames to print. Nothing must be sent to it after Stop().
+func (w *Worker) Frames() chan<- Frame {
+	return w.frames
+}
+
+// Submit queues img to be printed and returns the channel receiving its result (see Frame.Done).
+func (w *Worker) Submit(img image.Image) <-chan error {
+	done := make(chan error, 1)
+	w.frames <- Frame{Image: img, Done: done}
+	return done
+}
+
+// Stop stops receiving frames and waits until the last one received is printed.
+func (w *Worker) Stop() {
+	w.stop.Do(func() {
+		close(w.frames)
+	})
+	<-w.stopped
+}
+
+func (w *Worker) run(ctx context.Context) {
+	defer close(w.stopped)
+
+	var pending *Frame    // Newest frame waiting for the refresh in progress
+	var printing Frame    // Frame of the refresh in progress
+	var result chan error // Receives the result of the refresh in progress (nil if none)
+	frames, done := w.frames, ctx.Done()
+
+	for frames != nil || pending != nil || result != nil {
+		if result == nil && pending != nil {
+			printing, pending = *pending, nil
+			result = make(chan error, 1)
+			go func(img image.Image, result chan<- error) {
+				result <- w.e.printImage(ctx, img)
+			}(printing.Image, result)
+		}
+
+		select {
+		case f, ok := <-frames:
+			switch {
+			case !ok:
+				frames = nil
+			case ctx.Err() != nil:
+				f.report(ctx.Err())
+			default:
+				if pending != nil {
+					pending.report(ErrSuperseded)
+				}
+				pending = &f
+			}
+		case err := <-result:
+			printing.report(err)
+			result = nil
+		case <-done:
+			done = nil
+			if pending != nil {
+				pending.report(ctx.Err())
+				pending = nil
+			}
+		}
+	}
+}
+
+func (f *Frame) report(err error) {
+	if f.Done == nil {
+		return
+	}
+	select {
+	case f.Done <- err:
+	default:
+	}
+}
+
+// printImage draws img on Display and prints it.
+func (e *EPaper) printImage(ctx context.Context, img image.Image) error {
+	e.mu.Lock()
+	defer e.unlock()
+
+	e.displayMu.Lock()
+	draw.Draw(e.Display, img.Bounds(), img, img.Bounds().Min, draw.Src)
+	e.displayMu.Unlock()
+	return e.printDisplay(ctx)
+}
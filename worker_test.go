@@ -0,0 +1,241 @@
+package epaper_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mcules/go-epaper-lib"
+	"periph.io/x/periph/conn/gpio"
+)
+
+// filled returns an image of the size of ModelSim, with the band of rows [y0, y1) black.
+func filled(y0, y1 int) image.Image {
+	img := image.NewGray(image.Rect(0, 0, ModelSim.Width, ModelSim.Height))
+	for j := 0; j < ModelSim.Height; j++ {
+		for i := 0; i < ModelSim.Width; i++ {
+			c := color.Gray{Y: 0xff}
+			if j >= y0 && j < y1 {
+				c = color.Gray{}
+			}
+			img.SetGray(i, j, c)
+		}
+	}
+	return img
+}
+
+func TestWorker(t *testing.T) {
+	// Create a dummy "epaper"
+	// (to create a real one, use the example source code, this won't work!)
+	debug := new(bytes.Buffer)
+	e, err := epaper.NewCustom("", "", "", "", ModelSim, true, debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Forcing the BUSY to High to avoid being blocked because of WaitUntilIdle().
+	// Do not do this on real cases!
+	e.Busy.Out(gpio.High)
+
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.ClearScreen(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each refresh blocks until it is released, so the next frames queue up.
+	refreshing, release := make(chan struct{}), make(chan struct{})
+	e.Policy.OnRefresh = func(r image.Rectangle, partial bool) {
+		refreshing <- struct{}{}
+		<-release
+	}
+
+	w := e.StartWorker(context.Background())
+	first := w.Submit(filled(0, 1))
+	<-refreshing
+	second := w.Submit(filled(0, 2))
+	third := w.Submit(filled(0, 3))
+	if err := <-second; err != epaper.ErrSuperseded {
+		t.Fatalf("Expected the second frame to be superseded, found %v", err)
+	}
+
+	release <- struct{}{}
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+	<-refreshing
+	release <- struct{}{}
+	if err := <-third; err != nil {
+		t.Fatal(err)
+	}
+	w.Stop()
+
+	if n := countBlack(e.Snapshot(), image.Rect(0, 0, 8, ModelSim.Height)); n != 3*8 {
+		t.Fatalf("Expected the newest frame on screen (24 black pixels), found %d", n)
+	}
+}
+
+func TestWorkerCanceled(t *testing.T) {
+	// Create a dummy "epaper"
+	// (to create a real one, use the example source code, this won't work!)
+	debug := new(bytes.Buffer)
+	e, err := epaper.NewCustom("", "", "", "", ModelSim, true, debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := e.StartWorker(ctx)
+	cancel()
+	// Depending on when the cancellation is seen, the frame is refused or its refresh is canceled: the display was
+	// never initialized, so it can not succeed anyway.
+	if err := <-w.Submit(filled(0, 1)); err == nil {
+		t.Fatal("Expected an error")
+	}
+	w.Stop()
+}
+
+func TestConcurrentPrints(t *testing.T) {
+	// Create a dummy "epaper"
+	// (to create a real one, use the example source code, this won't work!)
+	debug := new(bytes.Buffer)
+	e, err := epaper.NewCustom("", "", "", "", ModelSim, true, debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Forcing the BUSY to High to avoid being blocked because of WaitUntilIdle().
+	// Do not do this on real cases!
+	e.Busy.Out(gpio.High)
+
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	debug.Reset()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			e.Draw(func(display draw.Image) {
+				display.Set(i, 0, color.Black)
+			})
+			if err := e.PrintDisplay(); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// The commands of each refresh were not interleaved: frame (0x13 + 40 bytes) then refresh (0x12), 4 times.
+	out := debug.Bytes()
+	for i := 0; i < 4; i++ {
+		frame := out[i*42 : (i+1)*42]
+		if frame[0] != 0x13 || frame[41] != 0x12 {
+			t.Fatalf("Expected 4 separate refreshes, found %v", out)
+		}
+	}
+}
+
+func TestPrintDisplayAsync(t *testing.T) {
+	// Create a dummy "epaper"
+	// (to create a real one, use the example source code, this won't work!)
+	debug := new(bytes.Buffer)
+	e, err := epaper.NewCustom("", "", "", "", ModelSim, true, debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Forcing the BUSY to High to avoid being blocked because of WaitUntilIdle().
+	// Do not do this on real cases!
+	e.Busy.Out(gpio.High)
+
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.ClearScreen(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The panel stays busy until BUSY goes high again.
+	e.Busy.Out(gpio.Low)
+	e.Draw(func(display draw.Image) {
+		draw.Draw(display, image.Rect(0, 0, 8, 1), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	})
+	done := e.PrintDisplayAsync()
+
+	// The next frame can be prepared meanwhile.
+	e.Draw(func(display draw.Image) {
+		draw.Draw(display, image.Rect(0, 1, 8, 2), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	})
+	select {
+	case err := <-done:
+		t.Fatalf("Expected the refresh to wait for the panel, found %v", err)
+	default:
+	}
+
+	e.Busy.Out(gpio.High)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if n := countBlack(e.Snapshot(), image.Rect(0, 0, 8, ModelSim.Height)); n != 8 {
+		t.Fatalf("Expected the frame captured by PrintDisplayAsync on screen (8 black pixels), found %d", n)
+	}
+}
+
+func TestPrintDisplayAsyncOrder(t *testing.T) {
+	// Create a dummy "epaper"
+	// (to create a real one, use the example source code, this won't work!)
+	e, err := epaper.NewCustom("", "", "", "", ModelSim, true, new(bytes.Buffer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Forcing the BUSY to High to avoid being blocked because of WaitUntilIdle().
+	// Do not do this on real cases!
+	e.Busy.Out(gpio.High)
+
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.ClearScreen(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second call returns at once, while the first refresh waits for the panel.
+	e.Busy.Out(gpio.Low)
+	returned := make(chan [2]<-chan error)
+	go func() {
+		e.Draw(func(display draw.Image) {
+			draw.Draw(display, image.Rect(0, 0, 8, 1), image.NewUniform(color.Black), image.Point{}, draw.Src)
+		})
+		first := e.PrintDisplayAsync()
+		e.Draw(func(display draw.Image) {
+			draw.Draw(display, image.Rect(0, 1, 8, 2), image.NewUniform(color.Black), image.Point{}, draw.Src)
+		})
+		returned <- [2]<-chan error{first, e.PrintDisplayAsync()}
+	}()
+	var done [2]<-chan error
+	select {
+	case done = <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("Expected PrintDisplayAsync to return while the panel is busy")
+	}
+
+	e.Busy.Out(gpio.High)
+	for i, d := range done {
+		if err := <-d; err != nil {
+			t.Fatalf("Frame %d: %v", i, err)
+		}
+	}
+	if n := countBlack(e.Snapshot(), image.Rect(0, 0, 8, ModelSim.Height)); n != 16 {
+		t.Fatalf("Expected the second frame on screen (16 black pixels), found %d", n)
+	}
+}